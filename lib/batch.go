@@ -0,0 +1,236 @@
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/dekelund/jsonrpc/lib/internal"
+)
+
+// Batch queues method calls and notifications to be sent as one message.
+type Batch struct {
+	client  *Client
+	methods []internal.Method
+	calls   []*Call // NOTE: Same index as methods, nil for notifications
+	err     error
+}
+
+// Batch returns an empty batch, queue calls with Call, CallNamed and
+// Notify and send them with Send.
+func (client *Client) Batch() *Batch {
+	return &Batch{client: client}
+}
+
+// Call queues a method call with positional parameters, the ID is
+// allocated by the client when the batch is sent.
+func (batch *Batch) Call(method string, params ...interface{}) *Call {
+	return batch.add(newCall(method, nil), internal.Method{Method: method, Params: params})
+}
+
+// CallWithID is like Call but uses the given ID.
+func (batch *Batch) CallWithID(id ID, method string, params ...interface{}) *Call {
+	return batch.add(newCall(method, &id), internal.Method{Method: method, Params: params})
+}
+
+// CallNamed queues a method call with named parameters.
+func (batch *Batch) CallNamed(method string, params interface{}) *Call {
+	return batch.add(newCall(method, nil), batch.named(method, params))
+}
+
+// CallNamedWithID is like CallNamed but uses the given ID.
+func (batch *Batch) CallNamedWithID(id ID, method string, params interface{}) *Call {
+	return batch.add(newCall(method, &id), batch.named(method, params))
+}
+
+// Notify queues a notification, the peer doesn't respond to it.
+func (batch *Batch) Notify(method string, params ...interface{}) {
+	batch.methods = append(batch.methods, internal.Method{Method: method, Params: params})
+	batch.calls = append(batch.calls, nil)
+}
+
+func (batch *Batch) add(call *Call, m internal.Method) *Call {
+	batch.methods = append(batch.methods, m)
+	batch.calls = append(batch.calls, call)
+
+	return call
+}
+
+func (batch *Batch) named(method string, params interface{}) internal.Method {
+	namedParams, err := internal.NamedParams(params)
+	if err != nil && batch.err == nil {
+		batch.err = err // NOTE: Returned by Send
+	}
+
+	return internal.Method{Method: method, NamedParams: namedParams}
+}
+
+// Send sends the queued calls and waits for all responses. Errors of the
+// individual calls are set on each Call, the returned error is set if the
+// batch couldn't be sent.
+func (batch *Batch) Send() error {
+	return batch.SendContext(context.Background())
+}
+
+// SendContext is like Send but stops waiting for the responses when ctx is
+// done, in which case ctx.Err() is returned, the calls still waiting for a
+// response are completed with ctx.Err() and late responses are dropped.
+func (batch *Batch) SendContext(ctx context.Context) error {
+	client := batch.client
+
+	if batch.err != nil {
+		return batch.err
+	} else if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	wg := sync.WaitGroup{}
+	sent := &sentBatch{}
+
+	complete := func(call *Call, result interface{}, err error) {
+		call.Result, call.Error = result, err
+		call.Done <- call
+		wg.Done()
+	}
+
+	var err error
+	done := make(chan bool)
+
+	if err := client.schedule(func() {
+		defer close(done)
+
+		// NOTE: Safe to use waiters, see Client.send
+		ids := make(map[ID]bool)
+		for _, call := range batch.calls {
+			if call != nil && !call.allocateID {
+				if client.isWaiting(call.ID) || ids[call.ID] {
+					err = errors.New("ID already used for outstanding request")
+					return
+				}
+
+				ids[call.ID] = true
+			}
+		}
+
+		for i, call := range batch.calls {
+			if call == nil {
+				continue
+			}
+
+			if call.allocateID {
+				if call.ID, err = client.allocateID(ids); err != nil {
+					return
+				}
+
+				ids[call.ID] = true
+			}
+
+			id := call.ID
+			batch.methods[i].ID = &id
+		}
+
+		if err = client.w.Batch(batch.methods); err != nil {
+			return
+		}
+
+		for _, call := range batch.calls {
+			if call == nil {
+				continue
+			}
+
+			call := call
+			wg.Add(1)
+
+			sent.calls = append(sent.calls, call)
+			client.waiters[call.ID] = func(r internal.Response) {
+				sent.answered = true
+				if sent.pending--; sent.pending == 0 {
+					client.removeBatch(sent)
+				}
+
+				result, err := response(r).result(client.opts)
+				complete(call, result, err)
+			}
+		}
+
+		if sent.pending = len(sent.calls); sent.pending > 0 {
+			client.batches = append(client.batches, sent)
+		}
+	}); err != nil {
+		return err
+	}
+
+	if <-done; err != nil {
+		return err
+	}
+
+	completed := make(chan bool)
+	go func() {
+		wg.Wait()
+		close(completed)
+	}()
+
+	select {
+	case <-completed:
+		return nil
+	case <-ctx.Done():
+	}
+
+	cancelled := func() {
+		client.removeBatch(sent)
+
+		for _, call := range sent.calls {
+			if client.isWaiting(call.ID) {
+				delete(client.waiters, call.ID)
+				complete(call, nil, ctx.Err())
+			}
+		}
+	}
+
+	if client.force(cancelled) {
+		<-completed
+	}
+
+	return ctx.Err()
+}
+
+// sentBatch is a sent batch with calls waiting for their responses.
+type sentBatch struct {
+	calls    []*Call
+	pending  int
+	answered bool // NOTE: Set once any of the calls has been responded to
+}
+
+// removeBatch removes a sent batch, it must be called from a scheduled
+// function.
+func (client *Client) removeBatch(sent *sentBatch) {
+	for i, b := range client.batches {
+		if b == sent {
+			client.batches = append(client.batches[:i], client.batches[i+1:]...)
+			return
+		}
+	}
+}
+
+// failBatch completes the pending calls of the oldest sent batch which
+// hasn't been responded to with the error of r, a response with a null ID
+// sent instead of the batch's responses, e.g. if the peer couldn't parse
+// it. It returns false if there is no such batch.
+func (client *Client) failBatch(r internal.Response) bool {
+	for _, sent := range client.batches {
+		if sent.answered {
+			continue
+		}
+
+		for _, call := range sent.calls {
+			if fn, ok := client.waiters[call.ID]; ok {
+				delete(client.waiters, call.ID)
+				fn(internal.Response{ID: call.ID, Error: r.Error})
+			}
+		}
+
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,136 @@
+package jsonrpc
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestBatch_Send(t *testing.T) {
+	cr, sw := io.Pipe()
+	sr, cw := io.Pipe()
+
+	go func() {
+		r := bufio.NewReader(sr)
+
+		expectedMSG := `[{"id":1,"method":"system.info","params":["cpu"]},{"method":"system.log","params":["hello"]},{"id":"b","method":"system.login","params":{"user":"root"}}]` + "\n"
+		if msg, _ := r.ReadString('\n'); msg != expectedMSG {
+			t.Errorf("Expected `%s`, received `%s`", expectedMSG, msg)
+		}
+
+		sw.Write([]byte(`[{"id":"b","error":{"code":-32000,"message":"Access denied"}},{"id":1,"result":75}]`))
+	}()
+
+	client := NewClient(cr, cw)
+
+	batch := client.Batch()
+	info := batch.Call("system.info", "cpu")
+	batch.Notify("system.log", "hello")
+	login := batch.CallNamedWithID(StringID("b"), "system.login", map[string]string{"user": "root"})
+
+	if err := batch.Send(); err != nil {
+		t.Errorf("Expected no errors, received %s", err.Error())
+		return
+	}
+
+	if info.Error != nil {
+		t.Errorf("Expected no error, received %s", info.Error.Error())
+		return
+	} else if v, ok := info.Result.(Number); !ok || v.String() != "75" {
+		t.Errorf("Expected 75, received %#v", info.Result)
+		return
+	}
+
+	if e, ok := login.Error.(Error); !ok || e.Code() != -32000 {
+		t.Errorf("Expected error code -32000, received %#v", login.Error)
+		return
+	}
+}
+
+func TestBatch_SendInvalidNamedParams(t *testing.T) {
+	cr, _ := io.Pipe()
+	_, cw := io.Pipe()
+
+	batch := NewClient(cr, cw).Batch()
+	batch.CallNamed("system.login", "root")
+
+	if err := batch.Send(); err == nil {
+		t.Error("Expected an error, received none")
+		return
+	}
+}
+
+func TestBatch_SendDuplicateID(t *testing.T) {
+	cr, _ := io.Pipe()
+	_, cw := io.Pipe()
+
+	batch := NewClient(cr, cw).Batch()
+	batch.CallWithID(IntID(1), "system.info")
+	batch.CallWithID(IntID(1), "system.info")
+
+	if err := batch.Send(); err == nil {
+		t.Error("Expected an error, received none")
+		return
+	}
+}
+
+func TestBatch_SendContextTimeout(t *testing.T) {
+	cr, sw := io.Pipe()
+	sr, cw := io.Pipe()
+
+	go func() {
+		r := bufio.NewReader(sr)
+		r.ReadString('\n')
+
+		sw.Write([]byte(`[{"id":1,"result":75}]`)) // NOTE: The response to the second call is missing
+	}()
+
+	batch := NewClient(cr, cw).Batch()
+	info := batch.CallWithID(IntID(1), "system.info")
+	load := batch.CallWithID(IntID(2), "system.load")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if err := batch.SendContext(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Expected context.DeadlineExceeded, received %v", err)
+		return
+	}
+
+	if info.Error != nil {
+		t.Errorf("Expected no error, received %s", info.Error.Error())
+	}
+
+	if load.Error != context.DeadlineExceeded {
+		t.Errorf("Expected context.DeadlineExceeded, received %v", load.Error)
+	}
+}
+
+func TestBatch_SendNullIDError(t *testing.T) {
+	cr, sw := io.Pipe()
+	sr, cw := io.Pipe()
+
+	go func() {
+		r := bufio.NewReader(sr)
+		r.ReadString('\n')
+
+		sw.Write([]byte(`{"id":null,"error":{"code":-32600,"message":"Invalid request"}}`))
+	}()
+
+	batch := NewClient(cr, cw).Batch()
+	info := batch.Call("system.info")
+	load := batch.Call("system.load")
+
+	if err := batch.Send(); err != nil {
+		t.Errorf("Expected no errors, received %s", err.Error())
+		return
+	}
+
+	for _, call := range []*Call{info, load} {
+		if e, ok := call.Error.(Error); !ok || e.Code() != CodeInvalidRequest {
+			t.Errorf("Expected error code %d, received %#v", CodeInvalidRequest, call.Error)
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package jsonrpc
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"io"
 	"sync"
@@ -9,28 +11,100 @@ import (
 	"time"
 )
 
+// Call is an asynchronous method call, see Client.Go, or a call of a
+// Batch. Result and Error are set when the call is completed, after which
+// the Call is sent on Done.
+type Call struct {
+	ID     ID
+	Method string
+	Result interface{}
+	Error  error
+	Done   chan *Call
+
+	allocateID bool
+}
+
+func newCall(method string, id *ID) *Call {
+	call := &Call{Method: method, Done: make(chan *Call, 1)}
+
+	if id == nil {
+		call.allocateID = true
+	} else {
+		call.ID = *id
+	}
+
+	return call
+}
+
+// complete sends the call on Done without blocking, e.g. the client's
+// go-routine. If Done is full the call is sent once there is room.
+func (call *Call) complete() {
+	select {
+	case call.Done <- call:
+	default:
+		go func() { call.Done <- call }()
+	}
+}
+
 type Client struct {
 	r       *internal.ResponseReader
 	w       *internal.MethodWriter
+	opts    options
 	calls   chan func()
-	waiters map[int64]func(internal.Response)
+	waiters map[ID]func(internal.Response)
+	closer  io.Closer // NOTE: Nil unless the client owns the connection, see Dial
+
+	methods *internal.MethodReader // NOTE: Notifications from the server, nil for a Peer
+	batches []*sentBatch           // NOTE: Batches waiting for responses, oldest first
+
+	subMutex      sync.Mutex
+	subscriptions map[ID]*ClientSubscription
+	subscribing   int               // NOTE: Subscribe calls waiting for their response
+	early         []internal.Method // NOTE: Notifications received while subscribing
 }
 
-func NewClient(r io.ReadCloser, w io.WriteCloser) *Client {
+func NewClient(r io.ReadCloser, w io.WriteCloser, opts ...Option) *Client {
+	o := newOptions(opts)
+
+	methods, responses := internal.NewFramedMessageReader(r, o.framer, o.maxFrameSize, 10)
+
+	client := newClient(responses, internal.NewFramedMethodWriter(w, o.framer, 10), o)
+	client.methods = methods
+
+	go client.serveNotifications()
+
+	return client
+}
+
+func newClient(r *internal.ResponseReader, w *internal.MethodWriter, opts options) *Client {
 	client := Client{
-		r:       internal.NewResponseReader(r, 10),
-		w:       internal.NewMethodWriter(w, 10),
+		r:       r,
+		w:       w,
+		opts:    opts,
 		calls:   make(chan func(), 10),
-		waiters: make(map[int64]func(internal.Response)),
+		waiters: make(map[ID]func(internal.Response)),
+
+		subscriptions: make(map[ID]*ClientSubscription),
 	}
 
+	client.w.Version = client.opts.wireVersion()
+
 	var eof bool
 
 	broadcastEOF := func() {
+		eofErr := internal.EOF
+		if client.opts.exitStatus != nil {
+			if err := client.opts.exitStatus(); err != nil {
+				eofErr = &internal.Error{Code: internal.EOF.Code, Message: err.Error()} // NOTE: The process failed
+			}
+		}
+
 		for id, fn := range client.waiters {
 			delete(client.waiters, id)
-			fn(internal.Response{id, internal.EOF, nil})
+			fn(internal.Response{ID: id, Error: eofErr})
 		}
+
+		client.closeSubscriptions(io.EOF)
 	}
 
 	go func() {
@@ -40,11 +114,22 @@ func NewClient(r io.ReadCloser, w io.WriteCloser) *Client {
 				if err == io.EOF {
 					eof = true
 					broadcastEOF()
+				} else if e, ok := err.(*internal.InvalidMessageError); ok {
+					if fn, ok := client.waiters[e.ID]; ok {
+						delete(client.waiters, e.ID)
+						fn(internal.Response{ID: e.ID, Error: errInvalidResponse})
+					}
 				}
 			case response := <-client.r.Responses:
+				if !client.valid(response) {
+					response.Error, response.Result = errInvalidResponse, nil
+				}
+
 				if fn, ok := client.waiters[response.ID]; ok {
 					delete(client.waiters, response.ID)
 					fn(response)
+				} else if response.ID.IsNull() && response.Error != nil {
+					client.failBatch(response) // NOTE: An error instead of the responses to a batch
 				}
 
 			case fn, more := <-client.calls:
@@ -69,6 +154,15 @@ func (client *Client) StopServing(max time.Duration) {
 	wg := sync.WaitGroup{}
 	wg.Add(2)
 
+	if client.methods != nil {
+		wg.Add(1)
+
+		go func() {
+			client.methods.StopServing(max) // Check and return err
+			wg.Done()
+		}()
+	}
+
 	// Don't use schedule, stop must be executed
 	client.calls <- func() {
 
@@ -82,12 +176,39 @@ func (client *Client) StopServing(max time.Duration) {
 			wg.Done()
 		}()
 
-
 		return
 	}
 
 	wg.Wait()
 	close(client.calls)
+
+	client.closeSubscriptions(ErrClientStopped)
+
+	if client.closer != nil {
+		client.closer.Close()
+	}
+}
+
+// serveNotifications passes the notifications sent by the server to the
+// subscriptions, other method calls are dropped.
+func (client *Client) serveNotifications() {
+	for {
+		select {
+		case method := <-client.methods.Methods:
+			client.notification(method)
+		case <-client.methods.Batches:
+		case <-client.methods.Errors:
+			// NOTE: The reader sends all notifications before EOF
+			for {
+				select {
+				case method := <-client.methods.Methods:
+					client.notification(method)
+				default:
+					return
+				}
+			}
+		}
+	}
 }
 
 func (client *Client) schedule(fn func()) (err error) {
@@ -105,50 +226,242 @@ func (client *Client) schedule(fn func()) (err error) {
 	}
 }
 
-func (client *Client) Call(id int64, method string, params ...interface{}) (result interface{}, err error) {
-	wg := sync.WaitGroup{}
-	if id != 0 {
-		wg.Add(1)
+// Call sends a method call with positional parameters and waits for the
+// response. The ID is allocated by the client, see WithIDGenerator.
+func (client *Client) Call(method string, params ...interface{}) (result interface{}, err error) {
+	return client.CallContext(context.Background(), method, params...)
+}
+
+// CallContext is like Call but stops waiting for the response when ctx is
+// done, in which case ctx.Err() is returned and a late response is dropped.
+func (client *Client) CallContext(ctx context.Context, method string, params ...interface{}) (result interface{}, err error) {
+	return client.call(ctx, nil, func(id *ID) error {
+		return client.w.Call(id, method, params...)
+	})
+}
+
+// CallInto is like CallContext but decodes the result into out, which
+// should be a pointer. A nil out discards the result.
+func (client *Client) CallInto(ctx context.Context, out interface{}, method string, params ...interface{}) error {
+	r, err := client.wait(ctx, nil, func(id *ID) error {
+		return client.w.Call(id, method, params...)
+	})
+	if err != nil {
+		return err
 	}
 
-	err = client.schedule(func() {
-		if err = client.w.Call(id, method, params...); err != nil {
-			wg.Done()
-			return
-		}
+	return r.decode(out, client.opts)
+}
+
+// CallWithID is like Call but sends the call with the given ID, which must
+// not be used by an outstanding call.
+func (client *Client) CallWithID(id ID, method string, params ...interface{}) (result interface{}, err error) {
+	return client.call(context.Background(), &id, func(id *ID) error {
+		return client.w.Call(id, method, params...)
+	})
+}
+
+// CallNamed is like Call but sends params, a map or a struct, as named
+// parameters.
+func (client *Client) CallNamed(method string, params interface{}) (result interface{}, err error) {
+	return client.call(context.Background(), nil, func(id *ID) error {
+		return client.w.CallNamed(id, method, params)
+	})
+}
+
+// CallNamedWithID is like CallNamed but sends the call with the given ID.
+func (client *Client) CallNamedWithID(id ID, method string, params interface{}) (result interface{}, err error) {
+	return client.call(context.Background(), &id, func(id *ID) error {
+		return client.w.CallNamed(id, method, params)
+	})
+}
+
+// Go sends a method call like Call but returns without waiting for the
+// response, the returned Call is sent on done when completed. If done is
+// nil a new channel is allocated, otherwise it may be shared by several
+// calls and must be buffered, as in net/rpc. Calls completed while done is
+// full are sent once there is room, they don't hold up the client.
+func (client *Client) Go(method string, done chan *Call, params ...interface{}) *Call {
+	call := newCall(method, nil)
 
+	if done == nil {
+		done = call.Done
+	} else if cap(done) == 0 {
+		panic("jsonrpc: done channel is unbuffered")
+	}
+
+	call.Done = done
+
+	if _, err := client.send(nil, func(id *ID) error {
+		call.ID = *id // NOTE: Set before the waiter is, which may complete the call
+		return client.w.Call(id, method, params...)
+	}, func(r response) {
+		call.Result, call.Error = r.result(client.opts)
+		call.complete()
+	}); err != nil {
+		call.Error = err
+		call.complete()
+	}
+
+	return call
+}
+
+// Notify sends a notification, a method call without ID which the peer
+// doesn't respond to.
+func (client *Client) Notify(method string, params ...interface{}) error {
+	errs := make(chan error, 1)
+
+	if err := client.schedule(func() {
+		errs <- client.w.Call(nil, method, params...)
+	}); err != nil {
+		return err
+	}
+
+	return <-errs
+}
+
+// call sends a method call with the given ID, or an allocated one if nil,
+// and waits for the result or for ctx to be done.
+func (client *Client) call(ctx context.Context, id *ID, write func(id *ID) error) (interface{}, error) {
+	r, err := client.wait(ctx, id, write)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.result(client.opts)
+}
+
+// wait sends a method call like call and waits for the response.
+func (client *Client) wait(ctx context.Context, id *ID, write func(id *ID) error) (response, error) {
+	if err := ctx.Err(); err != nil {
+		return response{}, err
+	}
+
+	responses := make(chan response, 1)
+
+	sent, err := client.send(id, write, func(r response) {
+		responses <- r
+	})
+	if err != nil {
+		return response{}, err
+	}
+
+	select {
+	case r := <-responses:
+		return r, nil
+	case <-ctx.Done():
+		client.forget(sent)
+		return response{}, ctx.Err()
+	}
+}
+
+// send writes a method call with the given ID, or an allocated one if nil,
+// and returns the ID. done is called with the response from the client's
+// go-routine, it must not block.
+func (client *Client) send(id *ID, write func(id *ID) error, done func(response)) (ID, error) {
+	errs := make(chan error, 1)
+
+	if err := client.schedule(func() {
 		// Since we runs the schedule at the same time as
 		// we handle responses, and since we only run one
 		// scheduled method at once, it's safe to read and
 		// write to waiters.
-		if _, ok := client.waiters[id]; ok {
-			err = errors.New("ID already used for outstanding request")
-			wg.Done()
+		if id == nil {
+			next, err := client.allocateID(nil)
+			if err != nil {
+				errs <- err
+				return
+			}
+
+			id = &next
+		} else if client.isWaiting(*id) {
+			errs <- errors.New("ID already used for outstanding request")
 			return
 		}
 
-		client.waiters[id] = func(r internal.Response) {
-			if r.Error == nil {
-				result = r.Result
-			} else {
-				err = Error{r.Error}
-			}
+		if err := write(id); err != nil {
+			errs <- err
+			return
+		}
 
-			if id != 0 {
-				wg.Done()
-			}
+		client.waiters[*id] = func(r internal.Response) {
+			done(response(r))
 		}
 
-		return
+		errs <- nil
+	}); err != nil {
+		return ID{}, err
+	}
+
+	if err := <-errs; err != nil {
+		return ID{}, err
+	}
+
+	return *id, nil
+}
+
+// forget removes the waiter for id, a late response is dropped.
+func (client *Client) forget(id ID) {
+	client.force(func() {
+		delete(client.waiters, id)
 	})
+}
 
-	if err != nil {
-		wg.Done()
+// force is like schedule but waits for room in the queue, for functions
+// which must be run. It returns false if the client has been stopped.
+func (client *Client) force(fn func()) (ok bool) {
+	defer func() {
+		if recover() != nil {
+			ok = false // NOTE: client.calls was closed, there are no waiters
+		}
+	}()
+
+	client.calls <- fn
+
+	return true
+}
+
+// allocateID returns an ID neither used by any outstanding call nor in
+// reserved, it must be called from a scheduled function.
+func (client *Client) allocateID(reserved map[ID]bool) (ID, error) {
+	// NOTE: Given distinct IDs, one of len(waiters)+len(reserved)+1 attempts is unused
+	for i := 0; i <= len(client.waiters)+len(reserved); i++ {
+		if id := client.opts.nextID(); !client.isWaiting(id) && !reserved[id] {
+			return id, nil
+		}
 	}
 
-	wg.Wait()
+	return ID{}, errors.New("Failed to allocate an unused ID")
+}
+
+func (client *Client) isWaiting(id ID) bool {
+	_, ok := client.waiters[id]
+	return ok
+}
+
+func (client *Client) valid(response internal.Response) bool {
+	return client.opts.version != Version2 || response.Version == internal.Version2
+}
+
+// response is a response received by the client.
+type response internal.Response
 
-	result = fixResultTypes(result)
+func (r response) result(opts options) (interface{}, error) {
+	if r.Error != nil {
+		return nil, opts.fromError(Error{r.Error})
+	}
+
+	return convertNumbers(r.Result, opts.numbers), nil
+}
+
+func (r response) decode(out interface{}, opts options) error {
+	if r.Error != nil {
+		return opts.fromError(Error{r.Error})
+	}
+
+	if out == nil || r.RawResult == nil {
+		return nil
+	}
 
-	return
+	return json.Unmarshal(r.RawResult, out)
 }
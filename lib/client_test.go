@@ -1,6 +1,8 @@
 package jsonrpc
 
 import (
+	"bufio"
+	"context"
 	"io"
 	"sync"
 	"testing"
@@ -25,7 +27,7 @@ func TestClient_Call(t *testing.T) {
 
 	client := NewClient(cr, cw)
 
-	result, err := client.Call(1, "system.info", "cpu", "mem")
+	result, err := client.Call("system.info", "cpu", "mem")
 	if err != nil {
 		t.Errorf("Expected no errors, received %s", err.Error())
 		return
@@ -73,9 +75,9 @@ func TestClient_StopServing(t *testing.T) {
 
 	client := NewClient(cr, cw)
 
-	client.Call(1, "system.info", "cpu", "mem") // Ignore result
+	client.CallWithID(IntID(1), "system.info", "cpu", "mem") // Ignore result
 	client.StopServing(0 * time.Second)
-	_, err := client.Call(2, "system.info", "cpu", "mem") // Ignore result
+	_, err := client.CallWithID(IntID(2), "system.info", "cpu", "mem") // Ignore result
 
 	if err != io.EOF {
 		t.Errorf("Expected EOF, received %v", err)
@@ -93,7 +95,7 @@ func TestClient_TooManyOutstandingReq(t *testing.T) {
 
 	for i := int64(1); i <= 12; i++ {
 		go func(i int64) {
-			if _, err := client.Call(i, "system.info", "cpu", "mem"); err == nil {
+			if _, err := client.CallWithID(IntID(i), "system.info", "cpu", "mem"); err == nil {
 				t.Error("Expected error, received none")
 			}
 			wg.Done()
@@ -114,7 +116,7 @@ func TestClient_ReuseID(t *testing.T) {
 
 	for i := 0; i < 2; i++ {
 		go func() {
-			if _, err := client.Call(1, "system.info", "cpu", "mem"); err == nil {
+			if _, err := client.CallWithID(IntID(1), "system.info", "cpu", "mem"); err == nil {
 				t.Error("Expected error, received none")
 			}
 			wg.Done()
@@ -133,7 +135,7 @@ func TestClient_CallBrokenPipe(t *testing.T) {
 	client := NewClient(cr, cw)
 
 	cr.Close()
-	client.Call(1, "system.info", "cpu", "mem")
+	client.CallWithID(IntID(1), "system.info", "cpu", "mem")
 	anoeuh
 }
 */
@@ -156,7 +158,7 @@ func TestClient_Call_rpcError(t *testing.T) {
 
 	client := NewClient(cr, cw)
 
-	result, err := client.Call(1, "system.info", "cpu", "mem")
+	result, err := client.CallWithID(IntID(1), "system.info", "cpu", "mem")
 	if err == nil {
 		t.Errorf("Expected an error, received nil and result = %#v", result)
 		return
@@ -204,7 +206,7 @@ func TestClient_Call_dsServerSideClosed(t *testing.T) {
 
 	client := NewClient(cr, cw)
 
-	if _, err := client.Call(1, "system.info", "cpu", "mem"); err != nil {
+	if _, err := client.CallWithID(IntID(1), "system.info", "cpu", "mem"); err != nil {
 		t.Errorf("Expected no error, received %s", err.Error())
 		return
 	}
@@ -215,7 +217,7 @@ func TestClient_Call_dsServerSideClosed(t *testing.T) {
 
 	wg.Wait()
 
-	if _, err := client.Call(1, "system.info", "cpu", "mem"); err == nil {
+	if _, err := client.CallWithID(IntID(1), "system.info", "cpu", "mem"); err == nil {
 		t.Errorf("Expected an error, received nil")
 		return
 	}
@@ -236,3 +238,432 @@ func TestClient_Call_dsServerSideClosed(t *testing.T) {
 		}
 	*/
 }
+
+func TestClient_CallVersion2(t *testing.T) {
+	cr, sw := io.Pipe()
+	sr, cw := io.Pipe()
+
+	go func() {
+		r := bufio.NewReader(sr)
+
+		expectedMSG := `{"jsonrpc":"2.0","id":1,"method":"system.info","params":[]}` + "\n"
+		if msg, _ := r.ReadString('\n'); msg != expectedMSG {
+			t.Errorf("Expected `%s`, received `%s`", expectedMSG, msg)
+		}
+
+		sw.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"ok"}`))
+
+		r.ReadString('\n')
+		sw.Write([]byte(`{"id":2,"result":"ok"}`))
+	}()
+
+	client := NewClient(cr, cw, WithVersion(Version2))
+
+	if result, err := client.CallWithID(IntID(1), "system.info"); err != nil {
+		t.Errorf("Expected no errors, received %s", err.Error())
+		return
+	} else if result != "ok" {
+		t.Errorf("Expected `ok`, received %#v", result)
+		return
+	}
+
+	if _, err := client.CallWithID(IntID(2), "system.info"); err == nil {
+		t.Error("Expected an error for a response without version, received none")
+		return
+	}
+}
+
+func TestClient_CallStringID(t *testing.T) {
+	cr, sw := io.Pipe()
+	sr, cw := io.Pipe()
+
+	go func() {
+		r := bufio.NewReader(sr)
+
+		expectedMSG := `{"id":"req-1","method":"system.info","params":[]}` + "\n"
+		if msg, _ := r.ReadString('\n'); msg != expectedMSG {
+			t.Errorf("Expected `%s`, received `%s`", expectedMSG, msg)
+		}
+
+		sw.Write([]byte(`{"id":1,"result":"wrong"}{"id":"req-1","result":"ok"}`))
+	}()
+
+	client := NewClient(cr, cw)
+
+	if result, err := client.CallWithID(StringID("req-1"), "system.info"); err != nil {
+		t.Errorf("Expected no errors, received %s", err.Error())
+		return
+	} else if result != "ok" {
+		t.Errorf("Expected `ok`, received %#v", result)
+		return
+	}
+}
+
+func TestClient_CallNamed(t *testing.T) {
+	cr, sw := io.Pipe()
+	sr, cw := io.Pipe()
+
+	go func() {
+		r := bufio.NewReader(sr)
+
+		expectedMSG := `{"id":1,"method":"system.login","params":{"user":"root"}}` + "\n"
+		if msg, _ := r.ReadString('\n'); msg != expectedMSG {
+			t.Errorf("Expected `%s`, received `%s`", expectedMSG, msg)
+		}
+
+		sw.Write([]byte(`{"id":1,"result":true}`))
+	}()
+
+	client := NewClient(cr, cw)
+
+	if result, err := client.CallNamedWithID(IntID(1), "system.login", map[string]string{"user": "root"}); err != nil {
+		t.Errorf("Expected no errors, received %s", err.Error())
+		return
+	} else if result != true {
+		t.Errorf("Expected true, received %#v", result)
+		return
+	}
+
+	if _, err := client.CallNamedWithID(IntID(2), "system.login", []string{"root"}); err == nil {
+		t.Error("Expected an error for params not being an object, received none")
+		return
+	}
+}
+
+func TestClient_Notify(t *testing.T) {
+	cr, sw := io.Pipe()
+	sr, cw := io.Pipe()
+
+	go func() {
+		r := bufio.NewReader(sr)
+
+		for _, expectedMSG := range []string{
+			`{"method":"system.log","params":["hello"]}`,
+			`{"id":0,"method":"system.info","params":[]}`,
+		} {
+			if msg, _ := r.ReadString('\n'); msg != expectedMSG+"\n" {
+				t.Errorf("Expected `%s`, received `%s`", expectedMSG, msg)
+			}
+		}
+
+		sw.Write([]byte(`{"id":0,"result":"ok"}`))
+	}()
+
+	client := NewClient(cr, cw)
+
+	if err := client.Notify("system.log", "hello"); err != nil {
+		t.Errorf("Expected no errors, received %s", err.Error())
+		return
+	}
+
+	waiters := make(chan int, 1)
+	client.schedule(func() { waiters <- len(client.waiters) })
+
+	if n := <-waiters; n != 0 {
+		t.Errorf("Expected no waiters after notification, received %d", n)
+		return
+	}
+
+	if result, err := client.CallWithID(IntID(0), "system.info"); err != nil {
+		t.Errorf("Expected no errors, received %s", err.Error())
+		return
+	} else if result != "ok" {
+		t.Errorf("Expected `ok`, received %#v", result)
+		return
+	}
+}
+
+func TestClient_CallAllocatesID(t *testing.T) {
+	cr, sw := io.Pipe()
+	sr, cw := io.Pipe()
+
+	go func() {
+		r := bufio.NewReader(sr)
+
+		for _, expectedMSG := range []string{
+			`{"id":"a1","method":"system.info","params":[]}`,
+			`{"id":"a3","method":"system.info","params":[]}`,
+		} {
+			if msg, _ := r.ReadString('\n'); msg != expectedMSG+"\n" {
+				t.Errorf("Expected `%s`, received `%s`", expectedMSG, msg)
+			}
+		}
+
+		sw.Write([]byte(`{"id":"a3","result":"second"}{"id":"a1","result":"first"}`))
+	}()
+
+	ids := []ID{StringID("a1"), StringID("a1"), StringID("a3")}
+	client := NewClient(cr, cw, WithIDGenerator(func() (id ID) {
+		id, ids = ids[0], ids[1:]
+		return
+	}))
+
+	results := make(chan interface{}, 2)
+	go func() {
+		result, _ := client.Call("system.info")
+		results <- result
+	}()
+
+	// NOTE: Make sure the first call has been written
+	for waiting := false; !waiting; {
+		done := make(chan bool)
+		if err := client.schedule(func() { waiting = client.isWaiting(StringID("a1")); close(done) }); err == nil {
+			<-done
+		}
+	}
+
+	if result, err := client.Call("system.info"); err != nil {
+		t.Errorf("Expected no errors, received %s", err.Error())
+		return
+	} else if result != "second" {
+		t.Errorf("Expected `second`, received %#v", result)
+		return
+	}
+
+	if result := <-results; result != "first" {
+		t.Errorf("Expected `first`, received %#v", result)
+		return
+	}
+}
+
+func TestClient_CallContext(t *testing.T) {
+	cr, sw := io.Pipe()
+	sr, cw := io.Pipe()
+
+	late := make(chan bool)
+	go func() {
+		r := bufio.NewReader(sr)
+		r.ReadString('\n') // NOTE: Never answered in time
+
+		<-late
+		sw.Write([]byte(`{"id":1,"result":"late"}`))
+
+		r.ReadString('\n')
+		sw.Write([]byte(`{"id":2,"result":"ok"}`))
+	}()
+
+	client := NewClient(cr, cw)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, err := client.CallContext(ctx, "system.info"); err != context.DeadlineExceeded {
+		t.Errorf("Expected deadline exceeded, received %v", err)
+		return
+	}
+
+	waiting := make(chan bool, 1)
+	client.schedule(func() { waiting <- client.isWaiting(IntID(1)) })
+
+	if <-waiting {
+		t.Error("Expected the waiter to be removed")
+		return
+	}
+
+	close(late)
+
+	if result, err := client.Call("system.info"); err != nil {
+		t.Errorf("Expected no errors, received %s", err.Error())
+		return
+	} else if result != "ok" {
+		t.Errorf("Expected `ok`, received %#v", result)
+		return
+	}
+}
+
+func TestClient_CallContextCanceled(t *testing.T) {
+	cr, _ := io.Pipe()
+	_, cw := io.Pipe()
+
+	client := NewClient(cr, cw)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := client.CallContext(ctx, "system.info"); err != context.Canceled {
+		t.Errorf("Expected canceled, received %v", err)
+		return
+	}
+}
+
+func TestClient_Go(t *testing.T) {
+	cr, sw := io.Pipe()
+	sr, cw := io.Pipe()
+
+	go func() {
+		r := bufio.NewReader(sr)
+		r.ReadString('\n')
+		r.ReadString('\n')
+
+		sw.Write([]byte(`{"id":2,"result":"second"}`))
+		sw.Write([]byte(`{"id":1,"error":{"code":-32000,"message":"first failed"}}`))
+	}()
+
+	client := NewClient(cr, cw)
+
+	first := client.Go("system.info", nil, "cpu")
+	second := client.Go("system.info", nil, "mem")
+
+	if first.ID != IntID(1) || second.ID != IntID(2) {
+		t.Errorf("Expected IDs 1 and 2, received %s and %s", first.ID, second.ID)
+		return
+	}
+
+	if call := <-second.Done; call.Error != nil || call.Result != "second" {
+		t.Errorf("Expected `second`, received %#v", call)
+		return
+	}
+
+	if call := <-first.Done; call.Error == nil || call.Error.Error() != "first failed" {
+		t.Errorf("Expected error `first failed`, received %#v", call)
+		return
+	}
+}
+
+func TestClient_GoSharedDone(t *testing.T) {
+	cr, sw := io.Pipe()
+	sr, cw := io.Pipe()
+
+	go func() {
+		r := bufio.NewReader(sr)
+		r.ReadString('\n')
+		r.ReadString('\n')
+
+		sw.Write([]byte(`{"id":2,"result":"mem"}`))
+		sw.Write([]byte(`{"id":1,"result":"cpu"}`))
+	}()
+
+	client := NewClient(cr, cw)
+
+	done := make(chan *Call, 2)
+	client.Go("system.info", done, "cpu")
+	client.Go("system.info", done, "mem")
+
+	for _, expected := range []string{"mem", "cpu"} {
+		if call := <-done; call.Error != nil || call.Result != expected {
+			t.Errorf("Expected `%s`, received %#v", expected, call)
+			return
+		}
+	}
+}
+
+func TestClient_GoSharedDoneFull(t *testing.T) {
+	sr, cw := io.Pipe()
+	cr, sw := io.Pipe()
+
+	server := NewServer(sr, sw)
+	client := NewClient(cr, cw)
+	defer server.StopServing(time.Second)
+	defer client.StopServing(time.Second)
+
+	server.HandleFunc("system.info", func(req *Request) (interface{}, error) {
+		return req.Params[0], nil
+	})
+
+	done := make(chan *Call, 4) // NOTE: Smaller than the number of calls
+	for i := 0; i < 200; i++ {
+		client.Go("system.info", done, i)
+	}
+
+	for i := 0; i < 200; i++ {
+		select {
+		case <-done: // NOTE: Calls may fail if the writer's queue is full, but complete
+		case <-time.After(5 * time.Second):
+			t.Errorf("Expected 200 calls, received %d", i)
+			return
+		}
+	}
+}
+
+func TestClient_CallInto(t *testing.T) {
+	cr, sw := io.Pipe()
+	sr, cw := io.Pipe()
+
+	go func() {
+		r := bufio.NewReader(sr)
+		r.ReadString('\n')
+		sw.Write([]byte(`{"id":1,"result":{"cpu":75,"disks":[{"name":"sda","free":1024}]}}`))
+
+		r.ReadString('\n')
+		sw.Write([]byte(`{"id":2,"error":{"code":-32000,"message":"Unavailable"}}`))
+	}()
+
+	client := NewClient(cr, cw)
+
+	var info struct {
+		CPU   int `json:"cpu"`
+		Disks []struct {
+			Name string `json:"name"`
+			Free int64  `json:"free"`
+		} `json:"disks"`
+	}
+
+	if err := client.CallInto(context.Background(), &info, "system.info"); err != nil {
+		t.Errorf("Expected no errors, received %s", err.Error())
+		return
+	}
+
+	if info.CPU != 75 || len(info.Disks) != 1 || info.Disks[0].Name != "sda" || info.Disks[0].Free != 1024 {
+		t.Errorf("Expected decoded result, received %#v", info)
+		return
+	}
+
+	if err := client.CallInto(context.Background(), &info, "system.info"); err == nil {
+		t.Error("Expected an error, received none")
+		return
+	} else if e, ok := err.(Error); !ok || e.Code() != -32000 {
+		t.Errorf("Expected error code -32000, received %#v", err)
+		return
+	}
+}
+
+func TestClient_Call_rpcErrorData(t *testing.T) {
+	cr, sw := io.Pipe()
+	sr, cw := io.Pipe()
+
+	go func() {
+		bufio.NewReader(sr).ReadString('\n')
+		sw.Write([]byte(`{"id":1,"error":{"code":-32602,"message":"Invalid params","data":{"param":"cpu"}}}`))
+	}()
+
+	_, err := NewClient(cr, cw).Call("system.info", "cpu")
+
+	e, ok := err.(Error)
+	if !ok || e.Code() != CodeInvalidParams {
+		t.Errorf("Expected an invalid params error, received %#v", err)
+		return
+	}
+
+	var data struct {
+		Param string `json:"param"`
+	}
+
+	if err := e.Data(&data); err != nil || data.Param != "cpu" {
+		t.Errorf("Expected data with param `cpu`, received %#v", data)
+		return
+	}
+}
+
+func TestClient_Framer(t *testing.T) {
+	for _, framer := range []Framer{Stream, NDJSON, ContentLength, Netstring} {
+		sr, cw := io.Pipe()
+		cr, sw := io.Pipe()
+
+		server := NewServer(sr, sw, WithFramer(framer))
+		server.HandleFunc("system.info", func(req *Request) (interface{}, error) {
+			return req.Params[0], nil
+		})
+
+		client := NewClient(cr, cw, WithFramer(framer))
+
+		result, err := client.Call("system.info", "cpu")
+
+		client.StopServing(time.Second)
+		server.StopServing(time.Second)
+
+		if err != nil || result != "cpu" {
+			t.Errorf("Expected `cpu`, received %v, %v", result, err)
+			return
+		}
+	}
+}
@@ -0,0 +1,308 @@
+package jsonrpc
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/dekelund/jsonrpc/lib/internal"
+)
+
+// stopTimeout is how long a connection closed by its peer waits for the
+// reader and writer to stop.
+const stopTimeout = time.Second
+
+// conn serves the method calls read from one connection. The handlers are
+// shared by all connections of a server, the in-flight limit and the
+// sequential queues are per connection.
+type conn struct {
+	server *Server
+	r      *internal.MethodReader
+	w      *internal.ResponseWriter
+
+	inFlight  chan bool // NOTE: Nil if unlimited
+	sequences map[string]chan func()
+	running   sync.WaitGroup // NOTE: Only added to by the serve go-routine
+
+	subMutex      sync.Mutex
+	subscriptions map[ID]*Subscription
+
+	draining chan bool // NOTE: Closed by Server.Shutdown
+	drain    sync.Once
+	stopping sync.Once
+	done     chan bool // NOTE: Closed once the reader and writer are stopped
+}
+
+func newConn(server *Server, r *internal.MethodReader, w *internal.ResponseWriter) *conn {
+	c := conn{
+		server: server,
+		r:      r,
+		w:      w,
+
+		sequences: make(map[string]chan func()),
+		draining:  make(chan bool),
+		done:      make(chan bool),
+
+		subscriptions: make(map[ID]*Subscription),
+	}
+
+	c.w.Version = server.opts.wireVersion()
+	c.w.Blocking = true // NOTE: Handlers wait rather than drop responses
+
+	if server.opts.maxInFlight > 0 {
+		c.inFlight = make(chan bool, server.opts.maxInFlight)
+	}
+
+	return &c
+}
+
+// serve reads and dispatches method calls until the connection is closed,
+// stopped or drained. If closeOnEOF is set the connection is stopped once
+// the peer closes it and the running handlers have responded.
+func (c *conn) serve(closeOnEOF bool) {
+	eof, drained := false, false
+
+	defer func() {
+		c.running.Wait()
+		c.stopSequences()
+
+		if eof || drained {
+			c.closeSubscriptions() // NOTE: The client has disconnected
+		}
+
+		if drained || eof && closeOnEOF {
+			c.stop(stopTimeout)
+		}
+	}()
+
+	for {
+		select {
+		case <-c.r.Errors:
+			eof = true // NOTE: The reader only sends EOF
+
+			// NOTE: The reader sends all methods before EOF, handle what's left
+			for {
+				select {
+				case method := <-c.r.Methods:
+					c.dispatchMethod(method)
+				case batch := <-c.r.Batches:
+					c.dispatchBatch(batch)
+				default:
+					return
+				}
+			}
+		case method := <-c.r.Methods:
+			c.dispatchMethod(method)
+		case batch := <-c.r.Batches:
+			c.dispatchBatch(batch)
+		case _, more := <-c.w.Errors:
+			if !more {
+				return // Server.StopServing() has been called
+			}
+		case <-c.draining:
+			drained = true // NOTE: Stop once the running handlers have responded
+			return
+		}
+	}
+}
+
+// dispatch runs fn in a go-routine once the number of running handlers
+// is below the limits, see WithMaxInFlight and WithMaxInFlightTotal.
+func (c *conn) dispatch(fn func()) {
+	acquire(c.inFlight)
+	acquire(c.server.total)
+
+	c.running.Add(1)
+
+	go func() {
+		defer c.running.Done()
+		defer release(c.inFlight)
+		defer release(c.server.total)
+
+		fn()
+	}()
+}
+
+// dispatchMethod dispatches the method call, calls to sequential methods
+// are queued and handled one at a time in arrival order, see
+// WithSequential.
+func (c *conn) dispatchMethod(method internal.Method) {
+	if method.Err != nil {
+		c.dispatch(func() { c.respondInvalid(method.Err) }) // NOTE: Dispatched to keep the order of responses
+		return
+	}
+
+	if c.server.intercept != nil && c.server.intercept(method) {
+		return // NOTE: Subscription notification for a Peer's client
+	}
+
+	if pattern, ok := c.server.opts.sequence(method.Method); ok {
+		c.queue(pattern, func() { c.respond(method) })
+	} else {
+		c.dispatch(func() { c.respond(method) })
+	}
+}
+
+// queue runs fn once the calls queued before it for the same sequential
+// pattern have returned, see WithSequential.
+func (c *conn) queue(pattern string, fn func()) {
+	sequence, ok := c.sequences[pattern]
+	if !ok {
+		sequence = make(chan func(), 10)
+		c.sequences[pattern] = sequence
+
+		go func() {
+			for fn := range sequence {
+				acquire(c.inFlight)
+				acquire(c.server.total)
+
+				fn()
+
+				release(c.server.total)
+				release(c.inFlight)
+				c.running.Done()
+			}
+		}()
+	}
+
+	c.running.Add(1)
+	sequence <- fn
+}
+
+func (c *conn) stopSequences() {
+	for pattern, sequence := range c.sequences {
+		delete(c.sequences, pattern)
+		close(sequence)
+	}
+}
+
+func acquire(sem chan bool) {
+	if sem != nil {
+		sem <- true
+	}
+}
+
+func release(sem chan bool) {
+	if sem != nil {
+		<-sem
+	}
+}
+
+// tryAcquire acquires all the semaphores, or none if it would have to wait.
+func tryAcquire(sems ...chan bool) bool {
+	for i, sem := range sems {
+		if sem == nil {
+			continue
+		}
+
+		select {
+		case sem <- true:
+		default:
+			for _, acquired := range sems[:i] {
+				release(acquired)
+			}
+
+			return false
+		}
+	}
+
+	return true
+}
+
+func (c *conn) respond(method internal.Method) {
+	response, sub := c.server.call(method, c)
+
+	if !c.server.opts.notification(method) {
+		c.w.Respond(response.ID, response.Error, response.Result)
+	} // NOTE: Notification, the peer don't expect any response
+
+	if sub != nil {
+		sub.activate()
+	}
+}
+
+// respondInvalid responds to a message which couldn't be read, errors
+// other than invalid or truncated messages aren't responded to.
+func (c *conn) respondInvalid(err error) {
+	switch e := err.(type) {
+	case *internal.InvalidMessageError:
+		c.w.Respond(e.ID, errInvalidRequest, nil)
+	case *json.SyntaxError:
+		c.w.Respond(ID{}, errParse, nil)
+	}
+
+	if err == io.ErrUnexpectedEOF {
+		c.w.Respond(ID{}, errParse, nil) // NOTE: Truncated message
+	}
+}
+
+// dispatchBatch dispatches the calls of the batch and responds once all
+// have returned, see batchCall.run. Calls to sequential methods are queued
+// with the single calls matching the same pattern.
+func (c *conn) dispatchBatch(batch internal.Batch) {
+	b := c.server.newBatchCall(batch, c)
+
+	queued := false
+
+	b.pending = b.pending[:0]
+	for i, method := range batch.Methods {
+		if pattern, ok := c.server.opts.sequence(method.Method); ok {
+			i := i
+			c.queue(pattern, func() { b.call(i) })
+			queued = true
+		} else {
+			b.pending = append(b.pending, i)
+		}
+	}
+
+	if len(b.pending) > 0 {
+		c.dispatch(func() {
+			b.run(c.inFlight, c.server.total)
+
+			if !queued {
+				c.respondBatch(b.wait())
+			}
+		})
+	}
+
+	if queued {
+		// NOTE: Not holding a slot, the queued calls may need it
+		c.running.Add(1)
+
+		go func() {
+			defer c.running.Done()
+			c.respondBatch(b.wait())
+		}()
+	}
+}
+
+func (c *conn) respondBatch(responses []internal.Response, subs []*Subscription) {
+	if len(responses) > 0 {
+		c.w.RespondBatch(responses)
+	}
+
+	for _, sub := range subs {
+		sub.activate()
+	}
+}
+
+// shutdown makes the connection stop reading method calls, it's stopped
+// once the running handlers have responded.
+func (c *conn) shutdown() {
+	c.drain.Do(func() { close(c.draining) })
+}
+
+// stop stops the writer, after the queued responses have been written, and
+// then the reader. It's safe to call more than once.
+func (c *conn) stop(max time.Duration) {
+	c.stopping.Do(func() {
+		c.w.StopServing(max) // Check and return err
+		c.r.StopServing(max) // Check and return err
+
+		c.closeSubscriptions()
+
+		c.server.untrack(c)
+		close(c.done)
+	})
+}
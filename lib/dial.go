@@ -0,0 +1,29 @@
+package jsonrpc
+
+import (
+	"context"
+	"net"
+)
+
+// Dial connects to the address on the named network, see net.Dial, and
+// returns a client calling methods over the connection. The connection is
+// closed by StopServing.
+func Dial(network, address string, opts ...Option) (*Client, error) {
+	return DialContext(context.Background(), network, address, opts...)
+}
+
+// DialContext is like Dial but uses the context while connecting. Once
+// connected, the context doesn't affect the client.
+func DialContext(ctx context.Context, network, address string, opts ...Option) (*Client, error) {
+	var dialer net.Dialer
+
+	conn, err := dialer.DialContext(ctx, network, address)
+	if err != nil {
+		return nil, err
+	}
+
+	client := NewClient(conn, conn, opts...)
+	client.closer = conn
+
+	return client, nil
+}
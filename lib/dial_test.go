@@ -0,0 +1,107 @@
+package jsonrpc
+
+import (
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDial(t *testing.T) {
+	for _, network := range []string{"tcp", "unix"} {
+		address := "127.0.0.1:0"
+		if network == "unix" {
+			dir, err := ioutil.TempDir("", "jsonrpc")
+			if err != nil {
+				t.Errorf("Expected no error, received %s", err.Error())
+				return
+			}
+			defer os.RemoveAll(dir)
+
+			address = filepath.Join(dir, "jsonrpc.sock")
+		}
+
+		l, err := net.Listen(network, address)
+		if err != nil {
+			t.Errorf("Expected no error, received %s", err.Error())
+			return
+		}
+
+		server := NewServer(nil, nil)
+		defer server.StopServing(time.Second)
+
+		server.HandleFunc("system.info", func(req *Request) (interface{}, error) {
+			return network, nil
+		})
+
+		go server.Serve(l)
+
+		client, err := Dial(network, l.Addr().String())
+		if err != nil {
+			t.Errorf("Expected no error, received %s", err.Error())
+			return
+		}
+
+		result, err := client.Call("system.info")
+		client.StopServing(time.Second)
+
+		if err != nil {
+			t.Errorf("Expected no error, received %s", err.Error())
+			return
+		}
+
+		if result != network {
+			t.Errorf("Expected `%s`, received `%v`", network, result)
+			return
+		}
+	}
+}
+
+func TestDial_StopServing(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Errorf("Expected no error, received %s", err.Error())
+		return
+	}
+	defer l.Close()
+
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, _ := l.Accept()
+		accepted <- conn
+	}()
+
+	client, err := Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Errorf("Expected no error, received %s", err.Error())
+		return
+	}
+
+	conn := <-accepted
+	defer conn.Close()
+
+	client.StopServing(time.Second)
+
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := io.Copy(ioutil.Discard, conn); err != nil {
+		t.Errorf("Expected the connection to be closed, received %s", err.Error())
+	}
+}
+
+func TestDial_Refused(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Errorf("Expected no error, received %s", err.Error())
+		return
+	}
+
+	address := l.Addr().String()
+	l.Close()
+
+	if _, err := Dial("tcp", address); err == nil {
+		t.Error("Expected an error, received none")
+	}
+}
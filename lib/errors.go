@@ -0,0 +1,107 @@
+package jsonrpc
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrorMapper converts between Go errors and JSON-RPC errors, see
+// WithErrorMapper.
+type ErrorMapper interface {
+	// ToError converts an error returned by a handler, the result is sent
+	// as described by Handler.
+	ToError(err error) error
+
+	// FromError converts an error received by the client.
+	FromError(err Error) error
+}
+
+// ErrorMap is an ErrorMapper mapping registered errors to codes and back,
+// so errors.Is and errors.As match the same errors on both sides of a
+// connection. The zero value is an empty map.
+type ErrorMap struct {
+	mutex     sync.RWMutex
+	sentinels []sentinel
+	factories map[int]func(Error) error
+}
+
+type sentinel struct {
+	code int
+	err  error
+}
+
+// Register maps err, and errors wrapping it, to code on the server, and
+// errors with code to errors matching err on the client.
+func (m *ErrorMap) Register(code int, err error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.sentinels = append(m.sentinels, sentinel{code, err})
+}
+
+// RegisterFunc makes the client convert errors with code using fn, for
+// instance into a typed error. On the server typed errors should implement
+// CodedError.
+func (m *ErrorMap) RegisterFunc(code int, fn func(Error) error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.factories == nil {
+		m.factories = make(map[int]func(Error) error)
+	}
+
+	m.factories[code] = fn
+}
+
+func (m *ErrorMap) ToError(err error) error {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	var e Error
+	if errors.As(err, &e) {
+		return err
+	}
+
+	for _, s := range m.sentinels {
+		if errors.Is(err, s.err) {
+			return NewError(s.code, err.Error())
+		}
+	}
+
+	return err
+}
+
+func (m *ErrorMap) FromError(err Error) error {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	if fn, ok := m.factories[err.Code()]; ok {
+		return fn(err)
+	}
+
+	for _, s := range m.sentinels {
+		if s.code == err.Code() {
+			return &mappedError{err, s.err}
+		}
+	}
+
+	return err
+}
+
+// mappedError is an Error which also matches a registered error.
+type mappedError struct {
+	err      Error
+	sentinel error
+}
+
+func (err *mappedError) Error() string {
+	return err.err.Error()
+}
+
+func (err *mappedError) Is(target error) bool {
+	return target == err.sentinel
+}
+
+func (err *mappedError) Unwrap() error {
+	return err.err
+}
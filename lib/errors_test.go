@@ -0,0 +1,71 @@
+package jsonrpc
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+)
+
+var errNotFound = errors.New("Not found")
+
+func newErrorMap() *ErrorMap {
+	m := &ErrorMap{}
+	m.Register(-32004, errNotFound)
+	m.RegisterFunc(-32001, func(err Error) error {
+		var quota quotaError
+		if err.Data(&quota) != nil {
+			return err
+		}
+
+		return quota
+	})
+
+	return m
+}
+
+func TestErrorMap(t *testing.T) {
+	sr, cw := io.Pipe()
+	cr, sw := io.Pipe()
+
+	server := NewServer(sr, sw, WithErrorMapper(newErrorMap()))
+	defer server.StopServing(time.Second)
+
+	server.HandleFunc("user.get", func(req *Request) (interface{}, error) {
+		return nil, fmt.Errorf("user %v: %w", req.Params[0], errNotFound)
+	})
+
+	server.HandleFunc("user.create", func(req *Request) (interface{}, error) {
+		return nil, quotaError{10, 10}
+	})
+
+	server.HandleFunc("user.delete", func(req *Request) (interface{}, error) {
+		return nil, errors.New("Permission denied")
+	})
+
+	client := NewClient(cr, cw, WithErrorMapper(newErrorMap()))
+
+	_, err := client.Call("user.get", 42)
+	if !errors.Is(err, errNotFound) {
+		t.Errorf("Expected error to match errNotFound, received %#v", err)
+		return
+	}
+
+	var e Error
+	if !errors.As(err, &e) || e.Code() != -32004 || e.Error() != "user 42: Not found" {
+		t.Errorf("Expected an Error with code -32004, received %#v", err)
+		return
+	}
+
+	var quota quotaError
+	if _, err := client.Call("user.create"); !errors.As(err, &quota) || quota.Limit != 10 {
+		t.Errorf("Expected a quotaError, received %#v", err)
+		return
+	}
+
+	if _, err := client.Call("user.delete"); errors.Is(err, errNotFound) || !errors.Is(err, InternalError(nil)) {
+		t.Errorf("Expected an internal error, received %#v", err)
+		return
+	}
+}
@@ -0,0 +1,41 @@
+package jsonrpc
+
+import (
+	"github.com/dekelund/jsonrpc/lib/internal"
+)
+
+// Framer delimits the messages of a connection, see WithFramer. Custom
+// framers return a FrameReader and a FrameWriter for the connection.
+type Framer = internal.Framer
+
+// FrameReader reads one message at a time, an error ends the connection.
+type FrameReader = internal.FrameReader
+
+// FrameWriter writes one message at a time.
+type FrameWriter = internal.FrameWriter
+
+// DefaultMaxFrameSize is the largest message read by default, see
+// WithMaxFrameSize.
+const DefaultMaxFrameSize = internal.DefaultMaxFrameSize
+
+// ErrFrameTooLarge is returned by a FrameReader reading a message larger
+// than its maximum size.
+var ErrFrameTooLarge = internal.ErrFrameTooLarge
+
+// Framers selectable with WithFramer.
+var (
+	// Stream delimits messages as concatenated JSON values, each written
+	// followed by a newline. It's the default.
+	Stream = internal.Stream
+
+	// NDJSON delimits messages as newline-delimited JSON, one message per
+	// line, so a malformed line doesn't end the connection.
+	NDJSON = internal.NDJSON
+
+	// ContentLength delimits messages with a Content-Length header, as
+	// language servers do.
+	ContentLength = internal.ContentLength
+
+	// Netstring delimits messages as netstrings, e.g. `24:{"id":1,...},`.
+	Netstring = internal.Netstring
+)
@@ -0,0 +1,258 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+
+	"github.com/dekelund/jsonrpc/lib/internal"
+)
+
+// ServeHTTP handles a method call, or a batch, sent in the body of a POST
+// request and writes the response in the response body. Notifications, and
+// batches of notifications only, are answered with 204 No Content. Bodies
+// are limited in size, see WithMaxFrameSize.
+func (server *Server) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, req.Body, int64(server.opts.maxFrameSize)))
+	if e := (*http.MaxBytesError)(nil); errors.As(err, &e) {
+		http.Error(w, "Request entity too large", http.StatusRequestEntityTooLarge)
+		return
+	} else if err != nil {
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	acquire(server.total)
+	defer release(server.total)
+
+	var v interface{}
+
+	method, batch, err := internal.DecodeMethods(body)
+	switch e := err.(type) {
+	case nil:
+	case *internal.InvalidMessageError:
+		v = internal.Response{ID: e.ID, Error: errInvalidRequest}
+	default:
+		v = internal.Response{Error: errParse}
+	}
+
+	if method != nil {
+		if response, _ := server.call(*method, nil); !server.opts.notification(*method) {
+			v = response
+		}
+	}
+
+	if batch != nil {
+		b := server.newBatchCall(*batch, nil)
+		b.run(server.total)
+
+		if responses, _ := b.wait(); len(responses) > 0 {
+			v = responses
+		}
+	}
+
+	if v == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	b, err := json.Marshal(server.versioned(v))
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(b)
+}
+
+// versioned sets the jsonrpc member of the response, or of each response
+// of a batch.
+func (server *Server) versioned(v interface{}) interface{} {
+	switch r := v.(type) {
+	case internal.Response:
+		r.Version = server.opts.wireVersion()
+		return r
+	case []internal.Response:
+		for i := range r {
+			r[i].Version = server.opts.wireVersion()
+		}
+	}
+
+	return v
+}
+
+// NewHTTPClient returns a client sending each method call, or batch, in
+// the body of a POST request to url. Responses in the body are used
+// whatever the HTTP status, failed requests without them are completed with
+// an internal error. See WithHTTPClient to configure the requests.
+func NewHTTPClient(url string, opts ...Option) *Client {
+	o := newOptions(opts)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	t := &httpTransport{
+		url:     url,
+		client:  o.httpClient,
+		version: o.wireVersion(),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+
+	t.r, t.w = io.Pipe()
+
+	if t.client == nil {
+		t.client = http.DefaultClient
+	}
+
+	client := NewClient(t, t, append(opts[:len(opts):len(opts)], WithFramer(Stream))...) // NOTE: Bodies hold one message
+	client.closer = t
+
+	return client
+}
+
+// httpTransport posts each message written to it and makes the response
+// bodies available for reading, one after another.
+type httpTransport struct {
+	url     string
+	client  *http.Client
+	version string
+
+	ctx    context.Context
+	cancel context.CancelFunc // NOTE: Cancels outstanding requests on Close
+
+	r     *io.PipeReader
+	w     *io.PipeWriter
+	mutex sync.Mutex // NOTE: Keeps response bodies from interleaving
+}
+
+func (t *httpTransport) Read(p []byte) (int, error) {
+	return t.r.Read(p)
+}
+
+// Write posts the message in the background, the writer encodes one
+// message per call.
+func (t *httpTransport) Write(p []byte) (int, error) {
+	if t.ctx.Err() != nil {
+		return 0, io.ErrClosedPipe
+	}
+
+	go t.post(append([]byte(nil), p...))
+
+	return len(p), nil
+}
+
+func (t *httpTransport) Close() error {
+	t.cancel()
+	t.w.Close()
+
+	return t.r.Close()
+}
+
+func (t *httpTransport) post(body []byte) {
+	b, err := t.do(body)
+	if err != nil {
+		if t.ctx.Err() != nil {
+			return // NOTE: Closed, pending calls are completed with EOF
+		}
+
+		b = t.fail(body, err)
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.w.Write(b)
+}
+
+func (t *httpTransport) do(body []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(t.ctx, http.MethodPost, t.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNoContent {
+		return nil, nil
+	}
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	responses, err := t.decode(b)
+	if err != nil && resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP %s", resp.Status)
+	} // NOTE: Otherwise error responses are used whatever the status, e.g. 500
+
+	return responses, err
+}
+
+// decode decodes the responses of a body on their own, rather than as part
+// of the stream read by the client, and encodes them one per line. Once
+// decoded, a malformed body can't fail the calls of later bodies.
+func (t *httpTransport) decode(body []byte) ([]byte, error) {
+	responses, err := internal.DecodeResponses(body)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := bytes.Buffer{}
+	enc := json.NewEncoder(&buf)
+
+	for _, r := range responses {
+		if r.RawResult != nil {
+			r.Result = r.RawResult // NOTE: Passed on as received
+		}
+
+		if err := enc.Encode(r); err != nil {
+			return nil, err
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+// fail returns internal error responses to the method calls of the body.
+func (t *httpTransport) fail(body []byte, err error) []byte {
+	method, batch, _ := internal.DecodeMethods(body)
+
+	var methods []internal.Method
+	if method != nil {
+		methods = append(methods, *method)
+	} else if batch != nil {
+		methods = batch.Methods
+	}
+
+	buf := bytes.Buffer{}
+	enc := json.NewEncoder(&buf)
+
+	for _, m := range methods {
+		if m.ID != nil {
+			enc.Encode(internal.Response{Version: t.version, ID: *m.ID, Error: &internal.Error{Code: CodeInternalError, Message: err.Error()}})
+		}
+	}
+
+	return buf.Bytes()
+}
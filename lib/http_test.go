@@ -0,0 +1,172 @@
+package jsonrpc
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestServer_ServeHTTP(t *testing.T) {
+	server := NewServer(nil, nil, WithVersion(Version2))
+	server.HandleFunc("system.info", func(req *Request) (interface{}, error) {
+		return req.Params[0], nil
+	})
+
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	for _, test := range []struct {
+		body, expected string
+		status         int
+	}{
+		{`{"jsonrpc":"2.0","id":1,"method":"system.info","params":["cpu"]}`, `{"jsonrpc":"2.0","id":1,"result":"cpu"}`, http.StatusOK},
+		{`[{"jsonrpc":"2.0","id":1,"method":"system.info","params":["cpu"]},{"jsonrpc":"2.0","method":"system.info","params":["mem"]}]`, `[{"jsonrpc":"2.0","id":1,"result":"cpu"}]`, http.StatusOK},
+		{`{"jsonrpc":"2.0","method":"system.info","params":["cpu"]}`, ``, http.StatusNoContent},
+		{`{"jsonrpc":"2.0","id":1,`, `{"jsonrpc":"2.0","id":null,"error":{"code":-32700,"message":"Parse error"}}`, http.StatusOK},
+	} {
+		resp, err := http.Post(ts.URL, "application/json", strings.NewReader(test.body))
+		if err != nil {
+			t.Errorf("Expected no error, received %s", err.Error())
+			return
+		}
+
+		b, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != test.status || string(b) != test.expected {
+			t.Errorf("Expected %d `%s`, received %d `%s`", test.status, test.expected, resp.StatusCode, b)
+			return
+		}
+	}
+}
+
+func TestServer_ServeHTTPMethodNotAllowed(t *testing.T) {
+	ts := httptest.NewServer(NewServer(nil, nil))
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL)
+	if err != nil {
+		t.Errorf("Expected no error, received %s", err.Error())
+		return
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("Expected %d, received %d", http.StatusMethodNotAllowed, resp.StatusCode)
+	}
+}
+
+func TestServer_ServeHTTPTooLarge(t *testing.T) {
+	ts := httptest.NewServer(NewServer(nil, nil, WithMaxFrameSize(16)))
+	defer ts.Close()
+
+	resp, err := http.Post(ts.URL, "application/json", strings.NewReader(`{"id":1,"method":"system.info","params":[]}`))
+	if err != nil {
+		t.Errorf("Expected no error, received %s", err.Error())
+		return
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected %d, received %d", http.StatusRequestEntityTooLarge, resp.StatusCode)
+	}
+}
+
+func TestHTTPClient(t *testing.T) {
+	server := NewServer(nil, nil, WithVersion(Version2))
+	server.HandleFunc("system.info", func(req *Request) (interface{}, error) {
+		return req.Params[0], nil
+	})
+
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	client := NewHTTPClient(ts.URL, WithVersion(Version2))
+	defer client.StopServing(time.Second)
+
+	if result, err := client.Call("system.info", "cpu"); err != nil || result != "cpu" {
+		t.Errorf("Expected `cpu`, received %v, %v", result, err)
+		return
+	}
+
+	if err := client.Notify("system.info", "mem"); err != nil {
+		t.Errorf("Expected no error, received %s", err.Error())
+		return
+	}
+
+	batch := client.Batch()
+	cpu, mem := batch.Call("system.info", "cpu"), batch.Call("system.info", "mem")
+
+	if err := batch.Send(); err != nil {
+		t.Errorf("Expected no error, received %s", err.Error())
+		return
+	}
+
+	if cpu.Result != "cpu" || mem.Result != "mem" {
+		t.Errorf("Expected `cpu` and `mem`, received %v and %v", cpu.Result, mem.Result)
+	}
+}
+
+func TestHTTPClient_StatusError(t *testing.T) {
+	ts := httptest.NewServer(http.NotFoundHandler())
+	defer ts.Close()
+
+	client := NewHTTPClient(ts.URL)
+	defer client.StopServing(time.Second)
+
+	_, err := client.Call("system.info")
+
+	if e, ok := err.(Error); !ok || e.Code() != CodeInternalError || !strings.Contains(e.Error(), "404") {
+		t.Errorf("Expected an internal error, received %v", err)
+	}
+}
+
+func TestHTTPClient_InvalidBody(t *testing.T) {
+	var calls int32
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.Write([]byte("<html>Bad gateway</html>")) // NOTE: E.g. from a proxy, but 200 OK
+			return
+		}
+
+		w.Write([]byte(`{"id":2,"result":"ok"}`))
+	}))
+	defer ts.Close()
+
+	client := NewHTTPClient(ts.URL)
+	defer client.StopServing(time.Second)
+
+	if _, err := client.Call("system.info"); err == nil {
+		t.Error("Expected an error, received none")
+		return
+	} else if e, ok := err.(Error); !ok || e.Code() != CodeInternalError {
+		t.Errorf("Expected an internal error, received %v", err)
+		return
+	}
+
+	if result, err := client.Call("system.info"); err != nil || result != "ok" {
+		t.Errorf("Expected `ok`, received %v, %v", result, err)
+	}
+}
+
+func TestHTTPClient_ErrorStatus(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"id":1,"error":{"code":-32000,"message":"Unavailable"}}`))
+	}))
+	defer ts.Close()
+
+	client := NewHTTPClient(ts.URL)
+	defer client.StopServing(time.Second)
+
+	_, err := client.Call("system.info")
+
+	if e, ok := err.(Error); !ok || e.Code() != -32000 || e.Error() != "Unavailable" {
+		t.Errorf("Expected error code -32000, received %v", err)
+	}
+}
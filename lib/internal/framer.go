@@ -0,0 +1,254 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+)
+
+// DefaultMaxFrameSize is the largest message read by default, in bytes.
+const DefaultMaxFrameSize = 16 << 20
+
+// ErrFrameTooLarge is returned by a FrameReader reading a message larger
+// than its maximum size.
+var ErrFrameTooLarge = errors.New("Frame too large")
+
+// Framer delimits the messages of a stream. Frame readers fail with
+// ErrFrameTooLarge rather than read messages larger than maxSize bytes.
+type Framer interface {
+	NewFrameReader(r io.Reader, maxSize int) FrameReader
+	NewFrameWriter(w io.Writer) FrameWriter
+}
+
+// FrameReader reads one message at a time. An error is final, the reader
+// can't continue after it.
+type FrameReader interface {
+	ReadFrame() ([]byte, error)
+}
+
+// FrameWriter writes one message at a time, with a single call to the
+// underlying writer.
+type FrameWriter interface {
+	WriteFrame(msg []byte) error
+}
+
+// Stream frames messages as concatenated JSON values, each message is
+// written followed by a newline.
+var Stream Framer = streamFramer{}
+
+// NDJSON frames messages as newline-delimited JSON, one message per line.
+var NDJSON Framer = ndjsonFramer{}
+
+// ContentLength frames messages with a Content-Length header, as in the
+// Language Server Protocol.
+var ContentLength Framer = contentLengthFramer{}
+
+// Netstring frames messages as netstrings, e.g. `12:{"id":1,...},`.
+var Netstring Framer = netstringFramer{}
+
+type streamFramer struct{}
+
+func (streamFramer) NewFrameReader(r io.Reader, maxSize int) FrameReader {
+	lr := &limitedReader{r: r, max: int64(maxSize)}
+
+	dec := json.NewDecoder(lr)
+	dec.UseNumber()
+
+	return frameReaderFunc(func() ([]byte, error) {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return nil, err
+		} else if len(raw) > int(lr.max) {
+			return nil, ErrFrameTooLarge
+		}
+
+		lr.start = dec.InputOffset() // NOTE: The next message starts here
+
+		return raw, nil
+	})
+}
+
+// limitedReader fails once more than max bytes, plus the size of the
+// decoder's buffer, have been read since start.
+type limitedReader struct {
+	r     io.Reader
+	n     int64 // NOTE: Bytes read so far
+	start int64
+	max   int64
+}
+
+func (lr *limitedReader) Read(p []byte) (int, error) {
+	if lr.n-lr.start > lr.max {
+		return 0, ErrFrameTooLarge
+	}
+
+	n, err := lr.r.Read(p)
+	lr.n += int64(n)
+
+	return n, err
+}
+
+func (streamFramer) NewFrameWriter(w io.Writer) FrameWriter {
+	return frameWriterFunc(func(msg []byte) error {
+		_, err := w.Write(append(msg, '\n'))
+		return err
+	})
+}
+
+type ndjsonFramer struct{}
+
+func (ndjsonFramer) NewFrameReader(r io.Reader, maxSize int) FrameReader {
+	br := bufio.NewReader(r)
+
+	return frameReaderFunc(func() ([]byte, error) {
+		for {
+			line, err := readLine(br, maxSize)
+			if line = bytes.TrimSpace(line); len(line) > 0 {
+				return line, nil // NOTE: The error, if any, is returned by the next read
+			} else if err != nil {
+				return nil, err
+			}
+		}
+	})
+}
+
+func (ndjsonFramer) NewFrameWriter(w io.Writer) FrameWriter {
+	return frameWriterFunc(func(msg []byte) error {
+		_, err := w.Write(append(msg, '\n'))
+		return err
+	})
+}
+
+type contentLengthFramer struct{}
+
+func (contentLengthFramer) NewFrameReader(r io.Reader, maxSize int) FrameReader {
+	tr := textproto.NewReader(bufio.NewReader(r))
+
+	return frameReaderFunc(func() ([]byte, error) {
+		header, err := tr.ReadMIMEHeader()
+		if err == io.EOF && len(header) == 0 {
+			return nil, io.EOF
+		} else if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+
+		length, err := strconv.Atoi(header.Get("Content-Length"))
+		if err != nil || length < 0 {
+			return nil, errors.New("Invalid Content-Length header")
+		} else if length > maxSize {
+			return nil, ErrFrameTooLarge
+		}
+
+		msg := make([]byte, length)
+		if _, err := io.ReadFull(tr.R, msg); err != nil {
+			return nil, unexpectedEOF(err)
+		}
+
+		return msg, nil
+	})
+}
+
+func (contentLengthFramer) NewFrameWriter(w io.Writer) FrameWriter {
+	return frameWriterFunc(func(msg []byte) error {
+		_, err := w.Write(append([]byte(fmt.Sprintf("Content-Length: %d\r\n\r\n", len(msg))), msg...))
+		return err
+	})
+}
+
+type netstringFramer struct{}
+
+func (netstringFramer) NewFrameReader(r io.Reader, maxSize int) FrameReader {
+	br := bufio.NewReader(r)
+	digits := len(strconv.Itoa(maxSize)) + 1 // NOTE: Including the colon
+
+	return frameReaderFunc(func() ([]byte, error) {
+		prefix, err := readLimited(br, ':', digits)
+		if err == io.EOF && len(prefix) == 0 {
+			return nil, io.EOF
+		} else if err == ErrFrameTooLarge {
+			return nil, err
+		} else if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+
+		length, err := strconv.Atoi(string(prefix[:len(prefix)-1]))
+		if err != nil || length < 0 {
+			return nil, errors.New("Invalid netstring length")
+		} else if length > maxSize {
+			return nil, ErrFrameTooLarge
+		}
+
+		msg := make([]byte, length+1)
+		if _, err := io.ReadFull(br, msg); err != nil {
+			return nil, unexpectedEOF(err)
+		} else if msg[length] != ',' {
+			return nil, errors.New("Invalid netstring terminator")
+		}
+
+		return msg[:length], nil
+	})
+}
+
+func (netstringFramer) NewFrameWriter(w io.Writer) FrameWriter {
+	return frameWriterFunc(func(msg []byte) error {
+		_, err := w.Write(append(append([]byte(strconv.Itoa(len(msg))+":"), msg...), ','))
+		return err
+	})
+}
+
+type frameReaderFunc func() ([]byte, error)
+
+func (fn frameReaderFunc) ReadFrame() ([]byte, error) {
+	return fn()
+}
+
+type frameWriterFunc func(msg []byte) error
+
+func (fn frameWriterFunc) WriteFrame(msg []byte) error {
+	return fn(msg)
+}
+
+// readLine reads up to and including the next newline, or until EOF. A line
+// longer than max bytes, not counting the newline, is ErrFrameTooLarge.
+func readLine(br *bufio.Reader, max int) ([]byte, error) {
+	line, err := readLimited(br, '\n', max+1)
+	if err != nil && len(line) > max {
+		return nil, ErrFrameTooLarge // NOTE: No trailing newline
+	}
+
+	return line, err
+}
+
+// readLimited is like bufio.Reader.ReadBytes but fails with
+// ErrFrameTooLarge rather than read more than max bytes.
+func readLimited(br *bufio.Reader, delim byte, max int) ([]byte, error) {
+	var line []byte
+
+	for {
+		b, err := br.ReadSlice(delim)
+		if len(line)+len(b) > max {
+			return nil, ErrFrameTooLarge
+		}
+
+		line = append(line, b...)
+
+		if err != bufio.ErrBufferFull {
+			return line, err
+		}
+	}
+}
+
+// unexpectedEOF returns io.ErrUnexpectedEOF for an EOF in the middle of a
+// message.
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+
+	return err
+}
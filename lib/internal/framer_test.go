@@ -0,0 +1,101 @@
+package internal
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestFramer_RoundTrip(t *testing.T) {
+	for name, framer := range map[string]Framer{"stream": Stream, "ndjson": NDJSON, "content-length": ContentLength, "netstring": Netstring} {
+		buf := bytes.Buffer{}
+		w := framer.NewFrameWriter(&buf)
+
+		messages := []string{`{"id":1,"method":"a","params":[]}`, `[1,2]`, `"x"`}
+		for _, msg := range messages {
+			if err := w.WriteFrame([]byte(msg)); err != nil {
+				t.Errorf("%s: Expected no error, received %s", name, err.Error())
+				return
+			}
+		}
+
+		r := framer.NewFrameReader(&buf, DefaultMaxFrameSize)
+		for _, expected := range messages {
+			if msg, err := r.ReadFrame(); err != nil || string(msg) != expected {
+				t.Errorf("%s: Expected `%s`, received `%s`, %v", name, expected, msg, err)
+				return
+			}
+		}
+
+		if _, err := r.ReadFrame(); err != io.EOF {
+			t.Errorf("%s: Expected io.EOF, received %v", name, err)
+			return
+		}
+	}
+}
+
+func TestFramer_Wire(t *testing.T) {
+	for framer, expected := range map[Framer]string{
+		Stream:        "{}\n",
+		NDJSON:        "{}\n",
+		ContentLength: "Content-Length: 2\r\n\r\n{}",
+		Netstring:     "2:{},",
+	} {
+		buf := bytes.Buffer{}
+		framer.NewFrameWriter(&buf).WriteFrame([]byte(`{}`))
+
+		if buf.String() != expected {
+			t.Errorf("Expected `%q`, received `%q`", expected, buf.String())
+		}
+	}
+}
+
+func TestFramer_Truncated(t *testing.T) {
+	for framer, input := range map[Framer]string{
+		ContentLength: "Content-Length: 10\r\n\r\n{}",
+		Netstring:     "10:{}",
+	} {
+		if _, err := framer.NewFrameReader(strings.NewReader(input), DefaultMaxFrameSize).ReadFrame(); err != io.ErrUnexpectedEOF {
+			t.Errorf("Expected io.ErrUnexpectedEOF for `%q`, received %v", input, err)
+		}
+	}
+}
+
+func TestMethodReader_NDJSONInvalidLine(t *testing.T) {
+	r := ioutil.NopCloser(strings.NewReader("{\"id\":1,\n{\"id\":2,\"method\":\"a\",\"params\":[]}\n"))
+	reader := NewFramedMethodReader(r, NDJSON, DefaultMaxFrameSize, 10)
+
+	if err := (<-reader.Methods).Err; err == nil || err == io.EOF {
+		t.Errorf("Expected a syntax error, received %v", err)
+		return
+	}
+
+	if m := <-reader.Methods; m.Method != "a" {
+		t.Errorf("Expected method `a`, received `%s`", m.Method)
+	}
+}
+
+func TestFramer_TooLarge(t *testing.T) {
+	for framer, input := range map[Framer]string{
+		Stream:        `{"id":1,"method":"system.info","params":[]}`,
+		NDJSON:        `{"id":1,"method":"system.info","params":[]}` + "\n",
+		ContentLength: "Content-Length: 99999999999999\r\n\r\n{}",
+		Netstring:     "99999999999999:{},",
+	} {
+		if _, err := framer.NewFrameReader(strings.NewReader(input), 16).ReadFrame(); err != ErrFrameTooLarge {
+			t.Errorf("Expected ErrFrameTooLarge for `%q`, received %v", input, err)
+		}
+	}
+
+	r := NDJSON.NewFrameReader(strings.NewReader("{}\n[1,2]\n"), 2)
+	if msg, err := r.ReadFrame(); err != nil || string(msg) != "{}" {
+		t.Errorf("Expected `{}`, received `%s`, %v", msg, err)
+		return
+	}
+
+	if _, err := r.ReadFrame(); err != ErrFrameTooLarge {
+		t.Errorf("Expected ErrFrameTooLarge, received %v", err)
+	}
+}
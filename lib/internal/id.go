@@ -0,0 +1,98 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math"
+	"strconv"
+)
+
+// ID is a request ID, a JSON number, string or null. The ID keeps a
+// canonical JSON representation, so equal IDs are equal map keys however
+// they were encoded, e.g. "a" and "\u0061" or 1 and 1.0. The zero value is
+// null.
+type ID struct {
+	raw string
+}
+
+func IntID(id int64) ID {
+	return ID{strconv.FormatInt(id, 10)}
+}
+
+func StringID(id string) ID {
+	b, _ := json.Marshal(id)
+	return ID{string(b)}
+}
+
+// IsNull reports whether the ID is null.
+func (id ID) IsNull() bool {
+	return id.raw == "" || id.raw == "null"
+}
+
+// Int64 returns the ID as an integer, ok is false unless it's an integral
+// number.
+func (id ID) Int64() (v int64, ok bool) {
+	v, err := strconv.ParseInt(id.raw, 10, 64)
+	return v, err == nil
+}
+
+// String returns the JSON representation of the ID.
+func (id ID) String() string {
+	if id.raw == "" {
+		return "null"
+	}
+
+	return id.raw
+}
+
+func (id ID) MarshalJSON() ([]byte, error) {
+	return []byte(id.String()), nil
+}
+
+func (id *ID) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+
+	switch {
+	case bytes.Equal(data, []byte("null")):
+		*id = ID{}
+		return nil
+	case len(data) > 0 && data[0] == '"':
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+
+		*id = StringID(s) // NOTE: Re-encoded, escapes are canonical
+	default:
+		var n json.Number
+		if err := json.Unmarshal(data, &n); err != nil {
+			return errors.New("ID must be a number, string or null")
+		}
+
+		*id = ID{canonicalNumber(n.String())}
+	}
+
+	return nil
+}
+
+// canonicalNumber returns the shortest representation of a JSON number,
+// integers without fraction or exponent. Integers out of the range of
+// int64 are kept as is, rather than lose precision.
+func canonicalNumber(n string) string {
+	v, err := strconv.ParseInt(n, 10, 64)
+	if err == nil {
+		return strconv.FormatInt(v, 10)
+	} else if e, ok := err.(*strconv.NumError); ok && e.Err == strconv.ErrRange {
+		return n
+	}
+
+	f, err := strconv.ParseFloat(n, 64)
+	if err != nil {
+		return n
+	} else if f == math.Trunc(f) && math.Abs(f) < 1<<63 {
+		return strconv.FormatInt(int64(f), 10)
+	}
+
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
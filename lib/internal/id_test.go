@@ -0,0 +1,67 @@
+package internal
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestID_RoundTrip(t *testing.T) {
+	for _, raw := range []string{`1`, `-42`, `1.5`, `"abc"`, `"1"`, `null`} {
+		var id ID
+		if err := json.Unmarshal([]byte(raw), &id); err != nil {
+			t.Errorf("Expected no error for %s, received %s", raw, err.Error())
+			return
+		}
+
+		if b, _ := json.Marshal(id); string(b) != raw {
+			t.Errorf("Expected `%s`, received `%s`", raw, b)
+			return
+		}
+	}
+}
+
+func TestID_Invalid(t *testing.T) {
+	for _, raw := range []string{`true`, `[1]`, `{"id":1}`} {
+		var id ID
+		if err := json.Unmarshal([]byte(raw), &id); err == nil {
+			t.Errorf("Expected an error for %s, received none", raw)
+			return
+		}
+	}
+}
+
+func TestID_Compare(t *testing.T) {
+	if IntID(1) == StringID("1") {
+		t.Error("Expected number and string IDs to differ")
+	}
+
+	if IntID(1) != IntID(1) || StringID("a") != StringID("a") {
+		t.Error("Expected equal IDs to be equal")
+	}
+
+	if !(ID{}).IsNull() || IntID(0).IsNull() {
+		t.Error("Expected only the zero ID to be null")
+	}
+
+	if v, ok := IntID(42).Int64(); !ok || v != 42 {
+		t.Errorf("Expected 42, received %d", v)
+	}
+}
+
+func TestID_Canonical(t *testing.T) {
+	for raw, expected := range map[string]ID{
+		`"\u003ca\u003e"`: StringID("<a>"),
+		`"<a>"`:           StringID("<a>"),
+		`"\u0061"`:        StringID("a"),
+		`1.0`:             IntID(1),
+		`1e2`:             IntID(100),
+		`-0`:              IntID(0),
+	} {
+		var id ID
+		if err := json.Unmarshal([]byte(raw), &id); err != nil {
+			t.Errorf("Expected no error for %s, received %s", raw, err.Error())
+		} else if id != expected {
+			t.Errorf("Expected %s for %s, received %s", expected, raw, id)
+		}
+	}
+}
@@ -1,30 +1,104 @@
 package internal
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 )
 
+// Version2 is the value of the jsonrpc member in JSON-RPC 2.0 messages,
+// JSON-RPC 1.0 messages have no such member.
+const Version2 = "2.0"
+
 type Response struct {
-	ID     int64       `json:"id,omitempty"`
-	Error  *Error      `json:"error,omitempty"`
-	Result interface{} `json:"result,omitempty"`
+	Version string      `json:"jsonrpc,omitempty"`
+	ID      ID          `json:"id"`
+	Error   *Error      `json:"error,omitempty"`
+	Result  interface{} `json:"result,omitempty"`
+
+	// RawResult holds the undecoded result member of received responses.
+	RawResult json.RawMessage `json:"-"`
 }
 
 type Method struct {
-	ID     int64         `json:"id,omitempty"`
-	Method string        `json:"method"`
-	Params []interface{} `json:"params"`
+	Version string        `json:"jsonrpc,omitempty"`
+	ID      *ID           `json:"id,omitempty"` // NOTE: Notifications have no ID
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+
+	// NamedParams holds the params member if it's an object rather than
+	// an array, in which case Params is nil.
+	NamedParams json.RawMessage `json:"-"`
+
+	// Err is set by the readers, instead of the other members, for a message
+	// which couldn't be read, so it's handled in arrival order. It's an
+	// *InvalidMessageError, a *json.SyntaxError or an error of the framer.
+	Err error `json:"-"`
 }
 
 type Error struct {
-	Code    int    `json:"code"`
-	Message string `json:"message"`
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
 }
 
 func (err *Error) Error() string {
 	return err.Message
 }
 
+// MarshalJSON encodes NamedParams as the params member if set, otherwise
+// Params.
+func (m Method) MarshalJSON() ([]byte, error) {
+	var params interface{} = m.Params
+	if m.NamedParams != nil {
+		params = m.NamedParams
+	}
+
+	return json.Marshal(struct {
+		Version string      `json:"jsonrpc,omitempty"`
+		ID      *ID         `json:"id,omitempty"`
+		Method  string      `json:"method"`
+		Params  interface{} `json:"params"`
+	}{m.Version, m.ID, m.Method, params})
+}
+
+// UnmarshalJSON tells a null ID apart from a missing one, the latter is a
+// notification, and decodes an object params member into NamedParams.
+func (m *Method) UnmarshalJSON(data []byte) error {
+	type method Method
+
+	aux := struct {
+		*method
+		ID     json.RawMessage `json:"id"`
+		Params json.RawMessage `json:"params"`
+	}{method: (*method)(m)}
+
+	if err := decode(data, &aux); err != nil {
+		return err
+	}
+
+	m.Params, m.NamedParams = nil, nil
+	switch params := bytes.TrimSpace(aux.Params); {
+	case len(params) == 0 || bytes.Equal(params, []byte("null")):
+	case params[0] == '[':
+		if err := decode(params, &m.Params); err != nil {
+			return err
+		}
+	case params[0] == '{':
+		m.NamedParams = params
+	default:
+		return errors.New("params must be an array or an object")
+	}
+
+	m.ID = nil
+	if aux.ID != nil {
+		m.ID = new(ID)
+		return m.ID.UnmarshalJSON(aux.ID)
+	}
+
+	return nil
+}
+
 func (m Method) String() string {
 	b, err := json.Marshal(m)
 
@@ -35,27 +109,208 @@ func (m Method) String() string {
 	return string(b)
 }
 
+// MarshalJSON encodes either the error or the result member, the result
+// member is present even if the result is nil.
+func (r Response) MarshalJSON() ([]byte, error) {
+	if r.Error != nil {
+		return json.Marshal(struct {
+			Version string `json:"jsonrpc,omitempty"`
+			ID      ID     `json:"id"`
+			Error   *Error `json:"error"`
+		}{r.Version, r.ID, r.Error})
+	}
+
+	return json.Marshal(struct {
+		Version string      `json:"jsonrpc,omitempty"`
+		ID      ID          `json:"id"`
+		Result  interface{} `json:"result"`
+	}{r.Version, r.ID, r.Result})
+}
+
+// UnmarshalJSON keeps the undecoded result member in RawResult.
+func (r *Response) UnmarshalJSON(data []byte) error {
+	type response Response
+
+	aux := struct {
+		*response
+		Result json.RawMessage `json:"result"`
+	}{response: (*response)(r)}
+
+	if err := decode(data, &aux); err != nil {
+		return err
+	}
+
+	r.Result, r.RawResult = nil, aux.Result
+	if aux.Result != nil {
+		return decode(aux.Result, &r.Result)
+	}
+
+	return nil
+}
+
 func (r Response) String() string {
-	var err error
-	var str []byte
+	str, err := json.Marshal(r)
 
-	if r.Error != nil {
-		str, _ = json.Marshal(struct {
-			ID    int64  `json:"id"`
-			Error *Error `json:"error"`
-		}{r.ID, r.Error})
-	} else {
-		str, err = json.Marshal(struct {
-			ID     int64       `json:"id"`
-			Result interface{} `json:"result"`
-		}{r.ID, r.Result})
+	if err != nil {
+		panic(err.Error())
+	}
+
+	return string(str)
+}
+
+// InvalidMessageError is sent by the readers when a message is valid JSON
+// but can't be decoded into a method call or response.
+type InvalidMessageError struct {
+	ID  ID
+	Err error
+}
+
+func (err *InvalidMessageError) Error() string {
+	return "Invalid message: " + err.Err.Error()
+}
+
+// Batch is a batch of method calls, Invalid holds the calls that couldn't
+// be decoded.
+type Batch struct {
+	Methods []Method
+	Invalid []*InvalidMessageError
+}
+
+// valid returns a *json.SyntaxError if raw isn't a valid JSON value.
+func valid(raw []byte) error {
+	if json.Valid(raw) {
+		return nil
+	}
+
+	var v json.RawMessage
+	return json.Unmarshal(raw, &v)
+}
+
+// isBatch reports whether the message is a batch, i.e. a JSON array.
+func isBatch(raw json.RawMessage) bool {
+	raw = bytes.TrimSpace(raw)
+	return len(raw) > 0 && raw[0] == '['
+}
+
+// splitBatch returns the messages of a batch, an empty batch is invalid.
+func splitBatch(raw json.RawMessage) ([]json.RawMessage, error) {
+	var messages []json.RawMessage
+
+	if err := json.Unmarshal(raw, &messages); err != nil {
+		return nil, &InvalidMessageError{Err: err}
+	} else if len(messages) == 0 {
+		return nil, &InvalidMessageError{Err: errors.New("empty batch")}
+	}
+
+	return messages, nil
+}
+
+func unmarshalBatch(raw json.RawMessage) (Batch, error) {
+	messages, err := splitBatch(raw)
+	if err != nil {
+		return Batch{}, err
+	}
+
+	return unmarshalMethods(messages), nil
+}
+
+// unmarshalMethods decodes the method calls of a batch.
+func unmarshalMethods(messages []json.RawMessage) Batch {
+	batch := Batch{}
+
+	for _, message := range messages {
+		call := Method{}
+
+		if err := unmarshal(message, &call); err != nil {
+			batch.Invalid = append(batch.Invalid, err.(*InvalidMessageError))
+			continue
+		}
+
+		batch.Methods = append(batch.Methods, call)
+	}
+
+	return batch
+}
 
+// DecodeMethods decodes a message holding a single method call or a batch,
+// as sent in an HTTP request body. If the message isn't valid JSON the
+// error is a *json.SyntaxError, if it isn't a valid method call the error
+// is an InvalidMessageError.
+func DecodeMethods(data []byte) (*Method, *Batch, error) {
+	var raw json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, nil, err
+	}
+
+	if isBatch(raw) {
+		batch, err := unmarshalBatch(raw)
 		if err != nil {
-			panic(err.Error())
+			return nil, nil, err
+		}
+
+		return nil, &batch, nil
+	}
+
+	call := Method{}
+	if err := unmarshal(raw, &call); err != nil {
+		return nil, nil, err
+	}
+
+	return &call, nil, nil
+}
+
+// unmarshal decodes a single message, numbers are decoded as json.Number.
+// If the message can't be decoded, the error is an InvalidMessageError
+// with the message ID if there is one.
+// DecodeResponses decodes a message holding a single response or a batch
+// of responses, as received in an HTTP response body.
+func DecodeResponses(data []byte) ([]Response, error) {
+	var raw json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	messages := []json.RawMessage{raw}
+	if isBatch(raw) {
+		var err error
+		if messages, err = splitBatch(raw); err != nil {
+			return nil, err
 		}
 	}
 
-	return string(str)
+	responses := make([]Response, 0, len(messages))
+	for _, message := range messages {
+		response := Response{}
+
+		if err := unmarshal(message, &response); err != nil {
+			return nil, err
+		}
+
+		responses = append(responses, response)
+	}
+
+	return responses, nil
+}
+
+func unmarshal(data []byte, v interface{}) error {
+	if err := decode(data, v); err != nil {
+		var id struct {
+			ID ID `json:"id"`
+		}
+		json.Unmarshal(data, &id)
+
+		return &InvalidMessageError{id.ID, err}
+	}
+
+	return nil
 }
 
-var EOF = &Error{-1, "EOF"}
+var EOF = &Error{Code: -1, Message: "EOF"}
+
+// decode is like json.Unmarshal, but numbers are decoded as json.Number.
+func decode(data []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	return dec.Decode(v)
+}
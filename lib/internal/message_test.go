@@ -6,7 +6,7 @@ import (
 
 func TestMethod_String(t *testing.T) {
 	expected := `{"id":10,"method":"system.login","params":["root","changeme"]}`
-	method := Method{ID: 10, Method: "system.login", Params:[]interface{}{"root", "changeme"}}
+	method := Method{ID: intID(10), Method: "system.login", Params:[]interface{}{"root", "changeme"}}
 	if method.String() != expected {
 		t.Errorf("expected %s received %s", expected, method.String())
 	}
@@ -14,7 +14,7 @@ func TestMethod_String(t *testing.T) {
 
 func TestResponse_String(t *testing.T) {
 	expected := `{"id":10,"result":[true,"success"]}`
-	response := Response{ID: 10, Result: []interface{}{true, "success"}}
+	response := Response{ID: IntID(10), Result: []interface{}{true, "success"}}
 	if response.String() != expected {
 		t.Errorf("expected %s received %s", expected, response.String())
 	}
@@ -22,7 +22,7 @@ func TestResponse_String(t *testing.T) {
 
 func TestError_Error(t *testing.T) {
 	expected := `{"id":10,"error":{"code":-1,"message":"EOF"}}`
-	response := Response{ID: 10, Error: EOF}
+	response := Response{ID: IntID(10), Error: EOF}
 
 	if response.String() != expected {
 		t.Errorf("expected %s received %s", expected, response.String())
@@ -50,7 +50,7 @@ func TestResponse_StringInvalidResult(t *testing.T) {
 		}
 	}()
 
-	Response{ID: 10, Result: make(chan int, 1)}.String()
+	_ = Response{ID: IntID(10), Result: make(chan int, 1)}.String()
 }
 
 func TestMethod_StringInvalidParam(t *testing.T) {
@@ -60,5 +60,40 @@ func TestMethod_StringInvalidParam(t *testing.T) {
 		}
 	}()
 
-	Method{ID: 10, Method: "system.login", Params: []interface{}{make(chan int, 1)}}.String()
-}
\ No newline at end of file
+	_ = Method{ID: intID(10), Method: "system.login", Params: []interface{}{make(chan int, 1)}}.String()
+}
+func TestResponse_StringVersion2(t *testing.T) {
+	expected := `{"jsonrpc":"2.0","id":10,"result":null}`
+	response := Response{Version: Version2, ID: IntID(10)}
+	if response.String() != expected {
+		t.Errorf("expected %s received %s", expected, response.String())
+	}
+}
+
+func TestMethod_StringNamedParams(t *testing.T) {
+	expected := `{"id":10,"method":"system.login","params":{"user":"root"}}`
+	method := Method{ID: intID(10), Method: "system.login", NamedParams: []byte(`{"user":"root"}`)}
+	if method.String() != expected {
+		t.Errorf("expected %s received %s", expected, method.String())
+	}
+}
+
+func TestDecodeMethods(t *testing.T) {
+	if m, _, err := DecodeMethods([]byte(`{"id":1,"method":"system.info","params":[]}`)); err != nil || m == nil || m.Method != "system.info" {
+		t.Errorf("Expected a method call, received %v, %v", m, err)
+	}
+
+	if _, b, err := DecodeMethods([]byte(`[{"id":1,"method":"a","params":[]},1]`)); err != nil || b == nil || len(b.Methods) != 1 || len(b.Invalid) != 1 {
+		t.Errorf("Expected a batch with one invalid call, received %v, %v", b, err)
+	}
+
+	if _, _, err := DecodeMethods([]byte(`{"id":1,`)); err == nil {
+		t.Error("Expected a syntax error, received none")
+	}
+
+	if _, _, err := DecodeMethods([]byte(`{"id":1,"method":"a","params":1}`)); err == nil {
+		t.Error("Expected an invalid message error, received none")
+	} else if e, ok := err.(*InvalidMessageError); !ok || e.ID != IntID(1) {
+		t.Errorf("Expected an invalid message error for ID 1, received %v", err)
+	}
+}
@@ -0,0 +1,146 @@
+package internal
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+)
+
+// NewFramedMessageReader reads both method calls and responses from r, as
+// sent by a peer which calls methods as well as responds to calls. Method
+// calls are read by the returned MethodReader, responses by the returned
+// ResponseReader. Syntax errors and values which aren't objects are read as
+// invalid method calls, EOF is sent to both readers. Messages are at most
+// maxSize bytes.
+func NewFramedMessageReader(r io.ReadCloser, framer Framer, maxSize, chSize int) (*MethodReader, *ResponseReader) {
+	methods, responses := newMethodReader(chSize), newResponseReader(chSize)
+
+	r = &closeOnce{ReadCloser: r} // NOTE: Closed by whichever reader is stopped first
+
+	go readMessages(r, framer, maxSize, methods, responses)
+	go stop(r, methods.stopper, methods.stopped)
+	go stop(r, responses.stopper, responses.stopped)
+
+	return methods, responses
+}
+
+// readMessages reads the messages of r until EOF, method calls are sent to
+// methods and responses to responses. Either reader may be nil, all
+// messages are then read by the other. Errors are sent as invalid method
+// calls, or to responses if methods is nil, EOF is sent to both.
+func readMessages(r io.Reader, framer Framer, maxSize int, methods *MethodReader, responses *ResponseReader) {
+	fail := func(err error) {
+		if methods != nil {
+			methods.Methods <- Method{Err: err} // NOTE: In arrival order, see Method.Err
+		} else {
+			responses.Errors <- err
+		}
+	}
+
+	isCall := func(raw json.RawMessage) bool {
+		return responses == nil || methods != nil && isMethod(raw)
+	}
+
+	frames := framer.NewFrameReader(r, maxSize)
+
+	for {
+		raw, err := frames.ReadFrame()
+		if err != nil {
+			if err != io.EOF && err != io.ErrClosedPipe {
+				fail(err) // NOTE: The framer can't recover from syntax or read errors
+			}
+
+			if methods != nil {
+				methods.Errors <- io.EOF
+			}
+
+			if responses != nil {
+				responses.Errors <- io.EOF
+			}
+
+			return
+		}
+
+		if err := valid(raw); err != nil {
+			fail(err) // NOTE: Framed, the next message can still be read
+			continue
+		}
+
+		if isBatch(raw) {
+			messages, err := splitBatch(raw)
+			if err != nil {
+				fail(err)
+				continue
+			}
+
+			if isCall(messages[0]) {
+				methods.Batches <- unmarshalMethods(messages)
+				continue
+			}
+
+			for _, message := range messages {
+				readResponse(message, responses)
+			}
+
+			continue
+		}
+
+		if isCall(raw) {
+			call := Method{}
+
+			if err := unmarshal(raw, &call); err != nil {
+				call = Method{Err: err}
+			}
+
+			methods.Methods <- call
+			continue
+		}
+
+		readResponse(raw, responses)
+	}
+}
+
+func readResponse(raw json.RawMessage, responses *ResponseReader) {
+	response := Response{}
+
+	if err := unmarshal(raw, &response); err != nil {
+		responses.Errors <- err
+		return
+	}
+
+	responses.Responses <- response
+}
+
+// stop closes r, and then stopped, once stopper is closed.
+func stop(r io.Closer, stopper, stopped chan bool) {
+	for range stopper {
+	} // NOTE: Until closed, see StopServing
+
+	r.Close()
+	close(stopped)
+}
+
+// closeOnce closes a reader shared by the method and response readers once.
+type closeOnce struct {
+	io.ReadCloser
+	once sync.Once
+	err  error
+}
+
+func (c *closeOnce) Close() error {
+	c.once.Do(func() { c.err = c.ReadCloser.Close() })
+	return c.err
+}
+
+// isMethod reports whether the message is a method call rather than a
+// response. Objects without a method member are considered responses,
+// other values invalid method calls.
+func isMethod(raw json.RawMessage) bool {
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return true
+	}
+
+	_, method := m["method"]
+	return method
+}
@@ -0,0 +1,37 @@
+package internal
+
+import (
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestMessageReader_Demultiplex(t *testing.T) {
+	r := ioutil.NopCloser(strings.NewReader(`{"id":1,"method":"a","params":[]}{"id":1,"result":null}[{"id":2,"error":{"code":1,"message":"x"}}][{"id":3,"method":"b","params":[]}]`))
+	methods, responses := NewFramedMessageReader(r, Stream, DefaultMaxFrameSize, 10)
+
+	if m := <-methods.Methods; m.Method != "a" {
+		t.Errorf("Expected method `a`, received `%s`", m.Method)
+	}
+
+	if r := <-responses.Responses; r.ID != IntID(1) || r.Error != nil {
+		t.Errorf("Expected a result for ID 1, received %v", r)
+	}
+
+	if r := <-responses.Responses; r.ID != IntID(2) || r.Error == nil {
+		t.Errorf("Expected an error for ID 2, received %v", r)
+	}
+
+	if b := <-methods.Batches; len(b.Methods) != 1 || b.Methods[0].Method != "b" {
+		t.Errorf("Expected a batch calling `b`, received %v", b)
+	}
+
+	if err := <-methods.Errors; err != io.EOF {
+		t.Errorf("Expected io.EOF, received %v", err)
+	}
+
+	if err := <-responses.Errors; err != io.EOF {
+		t.Errorf("Expected io.EOF, received %v", err)
+	}
+}
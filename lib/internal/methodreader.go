@@ -1,7 +1,6 @@
 package internal
 
 import (
-	"encoding/json"
 	"errors"
 	"io"
 	"time"
@@ -11,53 +10,35 @@ type MethodReader struct {
 	stopper chan bool
 	stopped chan bool
 
-	Methods chan Method
-	Errors  chan error
+	Methods chan Method // NOTE: Including the messages which couldn't be read, see Method.Err
+	Batches chan Batch
+	Errors  chan error // NOTE: Only io.EOF, once the reader is done
 }
 
 func NewMethodReader(r io.ReadCloser, chSize int) *MethodReader {
-	reader := MethodReader{
+	return NewFramedMethodReader(r, Stream, DefaultMaxFrameSize, chSize)
+}
+
+// NewFramedMethodReader is like NewMethodReader but reads messages delimited by framer,
+// of at most maxSize bytes.
+func NewFramedMethodReader(r io.ReadCloser, framer Framer, maxSize, chSize int) *MethodReader {
+	reader := newMethodReader(chSize)
+
+	go readMessages(r, framer, maxSize, reader, nil)
+	go stop(r, reader.stopper, reader.stopped)
+
+	return reader
+}
+
+func newMethodReader(chSize int) *MethodReader {
+	return &MethodReader{
 		stopper: make(chan bool, 1),
 		stopped: make(chan bool, 1),
 
 		Methods: make(chan Method, chSize),
+		Batches: make(chan Batch, chSize),
 		Errors:  make(chan error, chSize),
 	}
-
-	go func() {
-		dec := json.NewDecoder(r)
-		dec.UseNumber()
-
-		for {
-			call := Method{}
-
-			if err := dec.Decode(&call); err == io.EOF || err == io.ErrClosedPipe {
-				reader.Errors <- io.EOF
-				break
-			} else if err != nil {
-				reader.Errors <- err // NOTE: Consider to break regardless of error
-				continue
-			}
-
-			reader.Methods <- call
-		}
-	}()
-
-	go func() {
-		for {
-			select {
-			case _, more := <-reader.stopper:
-				if !more {
-					r.Close()
-
-					close(reader.stopped)
-					return
-				}
-			}
-		}
-	}()
-
-	return &reader
 }
 
 func (reader MethodReader) StopServing(max time.Duration) error {
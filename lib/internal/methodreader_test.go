@@ -14,8 +14,8 @@ func TestNewMethodReader_firstMethod(t *testing.T) {
 	reader := NewMethodReader(r, 1)
 	rpcmsg := <-reader.Methods
 
-	if rpcmsg.ID != 1 {
-		t.Errorf("Expected ID 1, received %d", rpcmsg.ID)
+	if *rpcmsg.ID != IntID(1) {
+		t.Errorf("Expected ID 1, received %s", rpcmsg.ID)
 		return
 	}
 
@@ -46,8 +46,8 @@ func TestNewMethodReader_firstAndSecondMethods(t *testing.T) {
 
 	rpcmsg := <-reader.Methods
 
-	if rpcmsg.ID != 1 {
-		t.Errorf("Expected ID 1, received %d", rpcmsg.ID)
+	if *rpcmsg.ID != IntID(1) {
+		t.Errorf("Expected ID 1, received %s", rpcmsg.ID)
 		return
 	}
 
@@ -61,8 +61,8 @@ func TestNewMethodReader_firstAndSecondMethods(t *testing.T) {
 
 	rpcmsg = <-reader.Methods
 
-	if rpcmsg.ID != 2 {
-		t.Errorf("Expected ID 2, received %d", rpcmsg.ID)
+	if *rpcmsg.ID != IntID(2) {
+		t.Errorf("Expected ID 2, received %s", rpcmsg.ID)
 		return
 	}
 
@@ -73,7 +73,7 @@ func TestNewMethodReader_firstAndSecondMethods(t *testing.T) {
 		t.Errorf("Expected no error received %s", err.Error())
 		return
 	} else if v != 42 {
-		t.Errorf("Expected result 42, received %d", result)
+		t.Errorf("Expected result 42, received %d", v)
 		return
 	}
 
@@ -94,7 +94,7 @@ func TestNewMethodReader_methodWithError(t *testing.T) {
 
 	reader := NewMethodReader(r, 1)
 
-	rpcErr := <-reader.Errors
+	rpcErr := (<-reader.Methods).Err
 
 	if rpcErr == nil {
 		t.Error("Expected an error, received none")
@@ -158,3 +158,91 @@ func TestMethodReader_StopServingWithTimeout(t *testing.T) {
 		// All ok
 	}
 }
+
+func TestNewMethodReader_invalidMethod(t *testing.T) {
+	r, w := io.Pipe()
+	go func() {
+		io.WriteString(w, `{"id": 7, "method": "mymethod", "params": "firstparam"}{"id": 8, "method": "mymethod", "params": []}`)
+		w.Close()
+	}()
+
+	reader := NewMethodReader(r, 1)
+
+	if err, ok := (<-reader.Methods).Err.(*InvalidMessageError); !ok {
+		t.Errorf("Expected an InvalidMessageError, received %#v", err)
+		return
+	} else if err.ID != IntID(7) {
+		t.Errorf("Expected ID 7, received %s", err.ID)
+		return
+	}
+
+	if rpcmsg := <-reader.Methods; *rpcmsg.ID != IntID(8) {
+		t.Errorf("Expected ID 8, received %s", rpcmsg.ID)
+		return
+	}
+
+	if err := <-reader.Errors; err != io.EOF {
+		t.Errorf("Expected io.EOF, received %#v", err)
+		return
+	}
+}
+
+func TestNewMethodReader_nullAndMissingID(t *testing.T) {
+	r, w := io.Pipe()
+	go func() {
+		io.WriteString(w, `{"id": null, "method": "mymethod", "params": []}{"method": "mymethod", "params": []}`)
+		w.Close()
+	}()
+
+	reader := NewMethodReader(r, 1)
+
+	if rpcmsg := <-reader.Methods; rpcmsg.ID == nil || !rpcmsg.ID.IsNull() {
+		t.Errorf("Expected null ID, received %#v", rpcmsg.ID)
+		return
+	}
+
+	if rpcmsg := <-reader.Methods; rpcmsg.ID != nil {
+		t.Errorf("Expected no ID, received %s", rpcmsg.ID)
+		return
+	}
+}
+
+func TestNewMethodReader_namedParams(t *testing.T) {
+	r, w := io.Pipe()
+	go io.WriteString(w, `{"id": 1, "method": "mymethod", "params": {"user": "root"}}`)
+
+	reader := NewMethodReader(r, 1)
+	defer reader.StopServing(time.Second)
+
+	rpcmsg := <-reader.Methods
+
+	if rpcmsg.Params != nil {
+		t.Errorf("Expected no positional params, received %#v", rpcmsg.Params)
+		return
+	}
+
+	if string(rpcmsg.NamedParams) != `{"user": "root"}` {
+		t.Errorf("Expected named params, received `%s`", rpcmsg.NamedParams)
+		return
+	}
+}
+
+func TestNewMethodReader_batch(t *testing.T) {
+	r, w := io.Pipe()
+	go io.WriteString(w, `[{"id": 1, "method": "first", "params": []}, {"id": 2, "method": 3}]`)
+
+	reader := NewMethodReader(r, 1)
+	defer reader.StopServing(time.Second)
+
+	batch := <-reader.Batches
+
+	if len(batch.Methods) != 1 || batch.Methods[0].Method != "first" {
+		t.Errorf("Expected one valid method call, received %#v", batch.Methods)
+		return
+	}
+
+	if len(batch.Invalid) != 1 || batch.Invalid[0].ID != IntID(2) {
+		t.Errorf("Expected one invalid method call, received %#v", batch.Invalid)
+		return
+	}
+}
@@ -1,25 +1,39 @@
 package internal
 
 import (
+	"bytes"
 	"encoding/json"
 	"io"
+	"sync"
 	"time"
 
 	"github.com/pkg/errors"
 )
 
 type MethodWriter struct {
-	io      *json.Encoder
+	frames  FrameWriter
 	calls   chan func() error
 	stopper chan bool
 	stopped chan bool
 
+	mutex  sync.RWMutex
+	closed bool // NOTE: Set once stopping, method calls are no longer queued
+
 	Errors chan error
+
+	// Version is the jsonrpc member of written method calls, it's omitted if
+	// empty. Set it before the first call.
+	Version string
 }
 
 func NewMethodWriter(w io.WriteCloser, chSize int) *MethodWriter {
+	return NewFramedMethodWriter(w, Stream, chSize)
+}
+
+// NewFramedMethodWriter is like NewMethodWriter but delimits messages using framer.
+func NewFramedMethodWriter(w io.WriteCloser, framer Framer, chSize int) *MethodWriter {
 	writer := MethodWriter{
-		io:      json.NewEncoder(w),
+		frames:  framer.NewFrameWriter(w),
 		calls:   make(chan func() error, chSize),
 		stopper: make(chan bool, 1),
 		stopped: make(chan bool, 1),
@@ -32,8 +46,11 @@ func NewMethodWriter(w io.WriteCloser, chSize int) *MethodWriter {
 			select {
 			case _, more := <-writer.stopper:
 				if !more {
+					writer.mutex.Lock()
+					writer.closed = true
+					writer.mutex.Unlock()
+
 					close(writer.stopped)
-					close(writer.calls)
 					close(writer.Errors)
 					return
 				}
@@ -64,8 +81,20 @@ func (writer *MethodWriter) StopServing(max time.Duration) error {
 	}
 }
 
-func (writer *MethodWriter) call(id int64, method string, params ...interface{}) error {
-	err := writer.io.Encode(Method{ID: id, Method: method, Params: params})
+func (writer *MethodWriter) prepare(m Method) Method {
+	m.Version = writer.Version
+	if m.Params == nil && m.NamedParams == nil {
+		m.Params = []interface{}{} // NOTE: null isn't a valid params member
+	}
+
+	return m
+}
+
+func (writer *MethodWriter) write(v interface{}) error {
+	b, err := json.Marshal(v)
+	if err == nil {
+		err = writer.frames.WriteFrame(b)
+	}
 
 	if err == io.EOF || err == io.ErrClosedPipe {
 		return io.EOF
@@ -76,19 +105,62 @@ func (writer *MethodWriter) call(id int64, method string, params ...interface{})
 	return nil
 }
 
-func (writer *MethodWriter) Call(id int64, method string, params ...interface{}) (err error) {
-	defer func() {
-		if r := recover(); r != nil {
-			err = io.EOF // NOTE: If writer.calls was closed, assume EOF
-		}
-	}()
+func (writer *MethodWriter) schedule(v interface{}) error {
+	writer.mutex.RLock()
+	defer writer.mutex.RUnlock()
+
+	if writer.closed {
+		return io.EOF
+	}
 
 	select {
-	case writer.calls <- func() error { return writer.call(id, method, params...) }:
-		err = nil
+	case writer.calls <- func() error { return writer.write(v) }:
+		return nil
 	default:
-		err = errors.New("Too many outstanding requests")
+		return errors.New("Too many outstanding requests")
+	}
+}
+
+// Call writes a method call with positional parameters.
+func (writer *MethodWriter) Call(id *ID, method string, params ...interface{}) error {
+	return writer.schedule(writer.prepare(Method{ID: id, Method: method, Params: params}))
+}
+
+// CallNamed writes a method call with named parameters, params must encode
+// to a JSON object.
+func (writer *MethodWriter) CallNamed(id *ID, method string, params interface{}) error {
+	b, err := NamedParams(params)
+	if err != nil {
+		return err
+	}
+
+	return writer.schedule(writer.prepare(Method{ID: id, Method: method, NamedParams: b}))
+}
+
+// Batch writes the method calls as a single batch.
+func (writer *MethodWriter) Batch(methods []Method) error {
+	if len(methods) == 0 {
+		return errors.New("Empty batch")
+	}
+
+	batch := make([]Method, len(methods))
+	for i, m := range methods {
+		batch[i] = writer.prepare(m)
+	}
+
+	return writer.schedule(batch)
+}
+
+// NamedParams encodes params, which must encode to a JSON object.
+func NamedParams(params interface{}) (json.RawMessage, error) {
+	b, err := json.Marshal(params)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to encode named parameters")
+	}
+
+	if b = bytes.TrimSpace(b); len(b) == 0 || b[0] != '{' {
+		return nil, errors.New("Named parameters must be encoded as a JSON object")
 	}
 
-	return
+	return b, nil
 }
@@ -20,7 +20,7 @@ func TestNewMethodWriter_methodCall(t *testing.T) {
 	}()
 
 	writer := NewMethodWriter(w, 1)
-	writer.Call(1, "test.method", 42, "second parameter")
+	writer.Call(intID(1), "test.method", 42, "second parameter")
 
 	wg.Wait()
 
@@ -43,7 +43,7 @@ func TestNewMethodWriter_methodCallWithEOF(t *testing.T) {
 	r.Close()
 
 	writer := NewMethodWriter(w, 1)
-	writer.Call(1, "test.method", 42, "second parameter")
+	writer.Call(intID(1), "test.method", 42, "second parameter")
 	if err := <- writer.Errors; err != io.EOF {
 		t.Error("Expected an error, received none")
 		return
@@ -67,12 +67,12 @@ func TestNewMethodWriter_firstAndSecondMethods(t *testing.T) {
 
 	writer := NewMethodWriter(w, 2) // Two indicates buffer for two outstanding requests
 
-	if err := writer.Call(1, "test.method", 42, "second parameter"); err != nil {
+	if err := writer.Call(intID(1), "test.method", 42, "second parameter"); err != nil {
 		t.Errorf("No error expected, received %s", err.Error())
 		return
 	}
 
-	if err := writer.Call(3, "test.method2", 911, "help value"); err != nil {
+	if err := writer.Call(intID(3), "test.method2", 911, "help value"); err != nil {
 		t.Errorf("No error expected, received %s", err.Error())
 		return
 	}
@@ -99,12 +99,12 @@ func TestNewMethodWriter_outstandingRequests(t *testing.T) {
 	writer := NewMethodWriter(w, 1)
 	defer writer.StopServing(1 * time.Second)
 
-	if err := writer.Call(1, "test.method", 42, "second parameter"); err != nil {
+	if err := writer.Call(intID(1), "test.method", 42, "second parameter"); err != nil {
 		t.Errorf("No error expected, received %s", err.Error())
 		return
 	}
 
-	if err := writer.Call(3, "test.method2", 911, "help value"); err == nil {
+	if err := writer.Call(intID(3), "test.method2", 911, "help value"); err == nil {
 		t.Error("Expected error, received none")
 		return
 	}
@@ -123,7 +123,7 @@ func TestMethodWriter_StopServing(t *testing.T) {
 	}()
 
 	writer := NewMethodWriter(w, 1)
-	if err := writer.Call(1, "test.method", 42, "second parameter"); err != nil {
+	if err := writer.Call(intID(1), "test.method", 42, "second parameter"); err != nil {
 		t.Errorf("No error expected, received %s", err.Error())
 		return
 	}
@@ -139,7 +139,7 @@ func TestMethodWriter_StopServing(t *testing.T) {
 
 	writer.StopServing(1 * time.Second)
 
-	if err := writer.Call(2, "system.something", 42, "going to fail"); err != io.EOF {
+	if err := writer.Call(intID(2), "system.something", 42, "going to fail"); err != io.EOF {
 		t.Errorf("Expected io.EOF, received %v", err)
 		return
 	}
@@ -151,7 +151,7 @@ func TestMethodWriter_StopServingWithTimeout(t *testing.T) {
 	r.Close()
 
 	writer := NewMethodWriter(w, 1)
-	if err := writer.Call(1, "test.method", 42, "second parameter"); err != nil {
+	if err := writer.Call(intID(1), "test.method", 42, "second parameter"); err != nil {
 		t.Errorf("No error expected, received %s", err.Error())
 		return
 	}
@@ -163,7 +163,7 @@ func TestMethodWriter_StopServingWithTimeout(t *testing.T) {
 
 	time.Sleep(2 * time.Second)
 
-	if err := writer.Call(2, "system.something", 42, "going to fail"); err != io.EOF {
+	if err := writer.Call(intID(2), "system.something", 42, "going to fail"); err != io.EOF {
 		t.Errorf("Expected io.EOF, received %v", err)
 		return
 	}
@@ -174,7 +174,7 @@ func TestNewMethodWriter_callWithInvalidParam(t *testing.T) {
 	_, w := io.Pipe()
 	writer := NewMethodWriter(w, 1)
 
-	if err := writer.Call(1, "invalid.call", make(chan bool)); err != nil {
+	if err := writer.Call(intID(1), "invalid.call", make(chan bool)); err != nil {
 		t.Errorf("No error expected, received %s", err.Error())
 		return
 	}
@@ -184,3 +184,41 @@ func TestNewMethodWriter_callWithInvalidParam(t *testing.T) {
 		return
 	}
 }
+
+func intID(id int64) *ID {
+	v := IntID(id)
+	return &v
+}
+
+func TestMethodWriter_Batch(t *testing.T) {
+	r, w := io.Pipe()
+
+	expectedMSG := `[{"id":1,"method":"test.method","params":[]},{"method":"test.notify","params":[42]}]`
+
+	msg := make([]byte, len(expectedMSG))
+	done := make(chan bool)
+	go func() {
+		io.ReadFull(r, msg)
+		close(done)
+	}()
+
+	writer := NewMethodWriter(w, 1)
+	defer writer.StopServing(1 * time.Second)
+
+	if err := writer.Batch([]Method{{ID: intID(1), Method: "test.method"}, {Method: "test.notify", Params: []interface{}{42}}}); err != nil {
+		t.Errorf("No error expected, received %s", err.Error())
+		return
+	}
+
+	<-done
+
+	if string(msg) != expectedMSG {
+		t.Errorf("Expected `%s`, received `%s`", expectedMSG, msg)
+		return
+	}
+
+	if err := writer.Batch(nil); err == nil {
+		t.Error("Expected error for empty batch, received none")
+		return
+	}
+}
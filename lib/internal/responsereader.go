@@ -1,7 +1,6 @@
 package internal
 
 import (
-	"encoding/json"
 	"errors"
 	"io"
 	"time"
@@ -16,50 +15,28 @@ type ResponseReader struct {
 }
 
 func NewResponseReader(r io.ReadCloser, chSize int) *ResponseReader {
-	reader := ResponseReader{
+	return NewFramedResponseReader(r, Stream, DefaultMaxFrameSize, chSize)
+}
+
+// NewFramedResponseReader is like NewResponseReader but reads messages delimited by framer,
+// of at most maxSize bytes.
+func NewFramedResponseReader(r io.ReadCloser, framer Framer, maxSize, chSize int) *ResponseReader {
+	reader := newResponseReader(chSize)
+
+	go readMessages(r, framer, maxSize, nil, reader)
+	go stop(r, reader.stopper, reader.stopped)
+
+	return reader
+}
+
+func newResponseReader(chSize int) *ResponseReader {
+	return &ResponseReader{
 		stopper: make(chan bool, 1),
 		stopped: make(chan bool, 1),
 
 		Responses: make(chan Response, chSize),
 		Errors:    make(chan error, chSize),
 	}
-
-	go func() {
-		dec := json.NewDecoder(r)
-		dec.UseNumber()
-
-		for {
-			response := Response{}
-
-			if err := dec.Decode(&response); err != nil {
-				if err == io.EOF || err == io.ErrClosedPipe {
-					reader.Errors <- io.EOF
-					break
-				}
-
-				reader.Errors <- err
-
-				continue
-			}
-
-			reader.Responses <- response
-		}
-	}()
-
-	go func() {
-		for {
-			select {
-			case _, more := <-reader.stopper:
-				if !more {
-					r.Close()
-					close(reader.stopped)
-					return
-				}
-			}
-		}
-	}()
-
-	return &reader
 }
 
 func (reader ResponseReader) StopServing(max time.Duration) error {
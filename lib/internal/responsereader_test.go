@@ -14,8 +14,8 @@ func TestNewResponseReader_firstResponse(t *testing.T) {
 	reader := NewResponseReader(r, 1)
 	rpcmsg := <-reader.Responses
 
-	if rpcmsg.ID != 1 {
-		t.Errorf("Expected ID 1, received %d", rpcmsg.ID)
+	if rpcmsg.ID != IntID(1) {
+		t.Errorf("Expected ID 1, received %s", rpcmsg.ID)
 		return
 	}
 
@@ -46,8 +46,8 @@ func TestNewResponseReader_firstAndSecondResponses(t *testing.T) {
 
 	rpcmsg := <-reader.Responses
 
-	if rpcmsg.ID != 1 {
-		t.Errorf("Expected ID 1, received %d", rpcmsg.ID)
+	if rpcmsg.ID != IntID(1) {
+		t.Errorf("Expected ID 1, received %s", rpcmsg.ID)
 		return
 	}
 
@@ -61,8 +61,8 @@ func TestNewResponseReader_firstAndSecondResponses(t *testing.T) {
 
 	rpcmsg = <-reader.Responses
 
-	if rpcmsg.ID != 2 {
-		t.Errorf("Expected ID 2, received %d", rpcmsg.ID)
+	if rpcmsg.ID != IntID(2) {
+		t.Errorf("Expected ID 2, received %s", rpcmsg.ID)
 		return
 	}
 
@@ -73,7 +73,7 @@ func TestNewResponseReader_firstAndSecondResponses(t *testing.T) {
 		t.Errorf("Expected no error received %s", err.Error())
 		return
 	} else if v != 42 {
-		t.Errorf("Expected result 42, received %d", result)
+		t.Errorf("Expected result 42, received %d", v)
 		return
 	}
 
@@ -159,3 +159,45 @@ func TestResponseReader_StopServingWithTimeout(t *testing.T) {
 		// All ok
 	}
 }
+
+func TestNewResponseReader_batch(t *testing.T) {
+	r, w := io.Pipe()
+	go func() {
+		io.WriteString(w, `[{"id": 1, "result": "first"}, {"id": 2, "result": "second"}]`)
+		w.Close()
+	}()
+
+	reader := NewResponseReader(r, 2)
+
+	for _, expected := range []string{"first", "second"} {
+		if rpcmsg := <-reader.Responses; rpcmsg.Result != expected {
+			t.Errorf("Expected `%s`, received %#v", expected, rpcmsg.Result)
+			return
+		}
+	}
+
+	if err := <-reader.Errors; err != io.EOF {
+		t.Errorf("Expected io.EOF, received %#v", err)
+		return
+	}
+}
+
+func TestNewResponseReader_rawResult(t *testing.T) {
+	r, w := io.Pipe()
+	go io.WriteString(w, `{"id": 1, "result": {"cpu": 75}}`)
+
+	reader := NewResponseReader(r, 1)
+	defer reader.StopServing(time.Second)
+
+	rpcmsg := <-reader.Responses
+
+	if string(rpcmsg.RawResult) != `{"cpu": 75}` {
+		t.Errorf("Expected raw result, received `%s`", rpcmsg.RawResult)
+		return
+	}
+
+	if result, ok := rpcmsg.Result.(map[string]interface{}); !ok || result["cpu"] != json.Number("75") {
+		t.Errorf("Expected decoded result, received %#v", rpcmsg.Result)
+		return
+	}
+}
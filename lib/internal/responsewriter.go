@@ -4,21 +4,38 @@ import (
 	"encoding/json"
 	"errors"
 	"io"
+	"sync"
 	"time"
 )
 
 type ResponseWriter struct {
-	io      *json.Encoder
+	frames  FrameWriter
 	calls   chan func() error
 	stopper chan bool
 	stopped chan bool
 
+	mutex  sync.RWMutex
+	closed bool // NOTE: Set once stopping, responses are no longer queued
+
 	Errors chan error
+
+	// Version is the jsonrpc member of written responses, it's omitted if
+	// empty. Set it before the first response.
+	Version string
+
+	// Blocking makes Respond wait for room among the outstanding responses
+	// rather than failing. Set it before the first response.
+	Blocking bool
 }
 
 func NewResponseWriter(w io.WriteCloser, chSize int) *ResponseWriter {
+	return NewFramedResponseWriter(w, Stream, chSize)
+}
+
+// NewFramedResponseWriter is like NewResponseWriter but delimits messages using framer.
+func NewFramedResponseWriter(w io.WriteCloser, framer Framer, chSize int) *ResponseWriter {
 	writer := ResponseWriter{
-		io:      json.NewEncoder(w),
+		frames:  framer.NewFrameWriter(w),
 		calls:   make(chan func() error, chSize),
 		stopper: make(chan bool, 1),
 		stopped: make(chan bool, 1),
@@ -31,8 +48,12 @@ func NewResponseWriter(w io.WriteCloser, chSize int) *ResponseWriter {
 			select {
 			case _, more := <-writer.stopper:
 				if !more {
+					writer.mutex.Lock()
+					writer.closed = true
+					writer.mutex.Unlock()
+
+					writer.flush()
 					close(writer.stopped)
-					close(writer.calls)
 					close(writer.Errors)
 					return
 				}
@@ -47,7 +68,21 @@ func NewResponseWriter(w io.WriteCloser, chSize int) *ResponseWriter {
 	return &writer
 }
 
-func (writer ResponseWriter) StopServing(max time.Duration) error {
+// flush writes the responses queued before StopServing was called.
+func (writer *ResponseWriter) flush() {
+	for {
+		select {
+		case fn := <-writer.calls:
+			if err := fn(); err != nil {
+				return
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (writer *ResponseWriter) StopServing(max time.Duration) error {
 	if max != 0 {
 		close(writer.stopper)
 	} // NOTE: Don't close if no timeout, makes tests predictable
@@ -63,22 +98,64 @@ func (writer ResponseWriter) StopServing(max time.Duration) error {
 	}
 }
 
-func (writer ResponseWriter) respond(id int64, err *Error, result interface{}) error {
-	return writer.io.Encode(Response{ID: id, Error: err, Result: result})
+func (writer *ResponseWriter) write(v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	return writer.frames.WriteFrame(b)
 	// TODO wrap error message
 }
 
-func (writer ResponseWriter) Respond(id int64, jsonrpcErr *Error, result interface{}) (err error) {
-	defer func() {
-		if r := recover(); r != nil {
-			err = io.EOF // NOTE: If writer.calls was closed, assume EOF
+func (writer *ResponseWriter) schedule(v interface{}) error {
+	writer.mutex.RLock()
+	defer writer.mutex.RUnlock()
+
+	if writer.closed {
+		return io.EOF
+	}
+
+	fn := func() error { return writer.write(v) }
+
+	if writer.Blocking {
+		select {
+		case writer.calls <- fn:
+			return nil
+		case <-writer.stopper:
+			return io.EOF // NOTE: Stopping, let the writer take the lock
 		}
-	}()
+	}
 
 	select {
-	case writer.calls <- func() error { return writer.respond(id, jsonrpcErr, result) }:
+	case writer.calls <- fn:
 		return nil
 	default:
 		return errors.New("Too many outstanding requests")
 	}
 }
+
+func (writer *ResponseWriter) Respond(id ID, jsonrpcErr *Error, result interface{}) error {
+	return writer.schedule(Response{Version: writer.Version, ID: id, Error: jsonrpcErr, Result: result})
+}
+
+// Notify writes a notification with named parameters, e.g. to push events
+// to the peer.
+func (writer *ResponseWriter) Notify(method string, namedParams json.RawMessage) error {
+	return writer.schedule(Method{Version: writer.Version, Method: method, NamedParams: namedParams})
+}
+
+// RespondBatch writes the responses as a single batch.
+func (writer *ResponseWriter) RespondBatch(responses []Response) error {
+	if len(responses) == 0 {
+		return errors.New("Empty batch")
+	}
+
+	batch := make([]Response, len(responses))
+	for i, r := range responses {
+		r.Version = writer.Version
+		batch[i] = r
+	}
+
+	return writer.schedule(batch)
+}
@@ -20,7 +20,7 @@ func TestNewResponseWriter_response(t *testing.T) {
 	}()
 
 	writer := NewResponseWriter(w, 1)
-	writer.Respond(1, nil, "success")
+	writer.Respond(IntID(1), nil, "success")
 
 	wg.Wait()
 
@@ -47,7 +47,7 @@ func TestNewResponseWriter_error(t *testing.T) {
 	}()
 
 	writer := NewResponseWriter(w, 1)
-	writer.Respond(1, &Error{Code: -32601, Message: "Method not found"}, nil)
+	writer.Respond(IntID(1), &Error{Code: -32601, Message: "Method not found"}, nil)
 
 	wg.Wait()
 	expectedMSG := `{"id":1,"error":{"code":-32601,"message":"Method not found"}}`
@@ -74,8 +74,8 @@ func TestNewResponseWriter_firstAndSecondRespond(t *testing.T) {
 
 	writer := NewResponseWriter(w, 2) // Two indicates buffer for two outstanding requests
 
-	writer.Respond(1, &Error{Code: -32601, Message: "Method not found"}, nil)
-	writer.Respond(3, &Error{Code: -32603, Message: "Internal error"}, nil)
+	writer.Respond(IntID(1), &Error{Code: -32601, Message: "Method not found"}, nil)
+	writer.Respond(IntID(3), &Error{Code: -32603, Message: "Internal error"}, nil)
 
 	wg.Wait()
 
@@ -100,12 +100,12 @@ func TestNewResponseWriter_outstandingRequests(t *testing.T) {
 	writer := NewResponseWriter(w, 1)
 	defer writer.StopServing(1 * time.Second)
 
-	if err := writer.Respond(1, &Error{Code: -32601, Message: "Method not found"}, nil); err != nil {
+	if err := writer.Respond(IntID(1), &Error{Code: -32601, Message: "Method not found"}, nil); err != nil {
 		t.Errorf("No error expected, received %s", err.Error())
 		return
 	}
 
-	if err := writer.Respond(3, &Error{Code: -32603, Message: "Internal error"}, nil); err == nil {
+	if err := writer.Respond(IntID(3), &Error{Code: -32603, Message: "Internal error"}, nil); err == nil {
 		t.Error("Expected error, received none")
 		return
 	}
@@ -125,7 +125,7 @@ func TestResponseWriter_StopServing(t *testing.T) {
 	}()
 
 	writer := NewResponseWriter(w, 1)
-	writer.Respond(3, &Error{Code: -32603, Message: "Internal error"}, nil)
+	writer.Respond(IntID(3), &Error{Code: -32603, Message: "Internal error"}, nil)
 
 	wg.Wait()
 
@@ -138,7 +138,7 @@ func TestResponseWriter_StopServing(t *testing.T) {
 
 	writer.StopServing(1 * time.Second)
 
-	err := writer.Respond(3, &Error{Code: -32603, Message: "Internal error"}, nil)
+	err := writer.Respond(IntID(3), &Error{Code: -32603, Message: "Internal error"}, nil)
 	if err == nil {
 		t.Errorf("Expected error, received none")
 	}
@@ -154,7 +154,7 @@ func TestResponseWriter_StopServingWithTimeout(t *testing.T) {
 	go io.ReadFull(r, msg)
 
 	writer := NewResponseWriter(w, 1)
-	if err := writer.Respond(3, &Error{Code: -32603, Message: "Internal error"}, nil); err != nil {
+	if err := writer.Respond(IntID(3), &Error{Code: -32603, Message: "Internal error"}, nil); err != nil {
 		t.Errorf("No error expected, received %s", err.Error())
 		return
 	}
@@ -166,8 +166,25 @@ func TestResponseWriter_StopServingWithTimeout(t *testing.T) {
 
 	time.Sleep(2 * time.Second)
 
-	if err := writer.Respond(3, &Error{Code: -32603, Message: "Internal error"}, nil); err != io.EOF {
+	if err := writer.Respond(IntID(3), &Error{Code: -32603, Message: "Internal error"}, nil); err != io.EOF {
 		t.Errorf("Expected io.EOF, received %v", err)
 		return
 	}
 }
+
+func TestResponseWriter_Notify(t *testing.T) {
+	r, w := io.Pipe()
+
+	writer := NewResponseWriter(w, 1)
+	defer writer.StopServing(time.Second)
+
+	writer.Version = Version2
+	writer.Notify("prices_subscription", []byte(`{"subscription":"0x1","result":1}`))
+
+	expectedMSG := `{"jsonrpc":"2.0","method":"prices_subscription","params":{"subscription":"0x1","result":1}}`
+	msg := make([]byte, len(expectedMSG))
+
+	if io.ReadFull(r, msg); string(msg) != expectedMSG {
+		t.Errorf("Expected `%s`, received `%s`", expectedMSG, msg)
+	}
+}
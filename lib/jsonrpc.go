@@ -2,6 +2,9 @@ package jsonrpc
 
 import (
 	"encoding/json"
+	"errors"
+	"math"
+	"math/big"
 
 	"github.com/dekelund/jsonrpc/lib/internal"
 )
@@ -10,6 +13,32 @@ type Number struct {
 	json.Number
 }
 
+// ID is a request ID, a JSON number, string or null. IDs are comparable
+// and round-trip exactly, the zero value is null.
+type ID = internal.ID
+
+// IntID returns a number ID.
+func IntID(id int64) ID {
+	return internal.IntID(id)
+}
+
+// StringID returns a string ID.
+func StringID(id string) ID {
+	return internal.StringID(id)
+}
+
+// Standard error codes, the codes -32000 to -32099 are reserved for
+// implementation defined server errors.
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+)
+
+// Error is an error sent by the peer, or sent to the peer when returned
+// from a Handler.
 type Error struct {
 	e *internal.Error
 }
@@ -22,18 +51,169 @@ func (err Error) Error() string {
 	return err.e.Error()
 }
 
-func fixResultTypes(result interface{}) interface{} {
+// Is reports whether target is an Error with the same code, so errors.Is
+// matches errors by code, e.g. errors.Is(err, MethodNotFound(nil)).
+func (err Error) Is(target error) bool {
+	t, ok := target.(Error)
+	return ok && t.e != nil && err.e != nil && t.e.Code == err.e.Code
+}
+
+// Data decodes the data member of the error into v, an error is returned
+// if the error has no data.
+func (err Error) Data(v interface{}) error {
+	if err.e.Data == nil {
+		return errors.New("Error has no data")
+	}
+
+	return json.Unmarshal(err.e.Data, v)
+}
+
+// NewError returns an error which is sent with the given code and message
+// when returned from a Handler.
+func NewError(code int, message string) Error {
+	return Error{&internal.Error{Code: code, Message: message}}
+}
+
+// NewErrorWithData is like NewError but the error is sent with data, which
+// is omitted if it can't be encoded.
+func NewErrorWithData(code int, message string, data interface{}) Error {
+	err := NewError(code, message)
+
+	if data != nil {
+		err.e.Data, _ = json.Marshal(data)
+	}
+
+	return err
+}
+
+// ParseError returns a standard parse error, data is optional.
+func ParseError(data interface{}) Error {
+	return NewErrorWithData(CodeParseError, "Parse error", data)
+}
+
+// InvalidRequest returns a standard invalid request error, data is
+// optional.
+func InvalidRequest(data interface{}) Error {
+	return NewErrorWithData(CodeInvalidRequest, "Invalid Request", data)
+}
+
+// MethodNotFound returns a standard method not found error, data is
+// optional.
+func MethodNotFound(data interface{}) Error {
+	return NewErrorWithData(CodeMethodNotFound, "Method not found", data)
+}
+
+// InvalidParams returns a standard invalid params error, data is optional.
+func InvalidParams(data interface{}) Error {
+	return NewErrorWithData(CodeInvalidParams, "Invalid params", data)
+}
+
+// InternalError returns a standard internal error, data is optional.
+func InternalError(data interface{}) Error {
+	return NewErrorWithData(CodeInternalError, "Internal error", data)
+}
+
+// CodedError is implemented by custom error types that handlers return to
+// send a specific code and data. ErrorData returns nil if there is no data.
+type CodedError interface {
+	error
+	ErrorCode() int
+	ErrorData() interface{}
+}
+
+var (
+	errParse           = ParseError(nil).e
+	errInvalidRequest  = InvalidRequest(nil).e
+	errInvalidResponse = &internal.Error{Code: CodeInternalError, Message: "Invalid response"}
+	errMethodNotFound  = MethodNotFound(nil).e
+	errInvalidParams   = InvalidParams(nil).e
+)
+
+func toInternalError(err error) *internal.Error {
+	var e Error
+	var coded CodedError
+
+	if errors.As(err, &e) {
+		return e.e
+	} else if errors.As(err, &coded) {
+		internalErr := &internal.Error{Code: coded.ErrorCode(), Message: coded.Error()}
+
+		if data := coded.ErrorData(); data != nil {
+			b, err := json.Marshal(data)
+			if err != nil {
+				return &internal.Error{Code: CodeInternalError, Message: err.Error()}
+			}
+
+			internalErr.Data = b
+		}
+
+		return internalErr
+	}
+
+	return &internal.Error{Code: CodeInternalError, Message: err.Error()}
+}
+
+// NumberMode selects how numbers in call results are represented.
+type NumberMode int
+
+const (
+	// NumbersAsNumber represents numbers as Number, the default.
+	NumbersAsNumber NumberMode = iota
+
+	// NumbersAsFloat64 represents numbers as float64.
+	NumbersAsFloat64
+
+	// NumbersAsInt64 represents integral numbers that fit as int64, e.g. 1,
+	// 1.0 or 1e3, other numbers as float64.
+	NumbersAsInt64
+
+	// NumbersAsBig represents integers as *big.Int, other numbers as
+	// *big.Float.
+	NumbersAsBig
+)
+
+// convertNumbers replaces every json.Number in result, including the ones
+// nested in objects and arrays, according to mode.
+func convertNumbers(result interface{}, mode NumberMode) interface{} {
 	switch r := result.(type) {
 	case json.Number:
-		result = Number{r}
+		return convertNumber(r, mode)
 	case []interface{}:
 		for i, v := range r {
-			switch v := v.(type) {
-			case json.Number:
-				r[i] = Number{v}
-			}
+			r[i] = convertNumbers(v, mode)
+		}
+	case map[string]interface{}:
+		for k, v := range r {
+			r[k] = convertNumbers(v, mode)
 		}
 	}
 
 	return result
 }
+
+func convertNumber(n json.Number, mode NumberMode) interface{} {
+	switch mode {
+	case NumbersAsFloat64:
+		if f, err := n.Float64(); err == nil {
+			return f
+		}
+	case NumbersAsInt64:
+		if i, err := n.Int64(); err == nil {
+			return i
+		} else if f, err := n.Float64(); err == nil {
+			if f == math.Trunc(f) && f >= math.MinInt64 && f < math.MaxInt64 {
+				return int64(f) // NOTE: Integral, e.g. 1.0 or 1e3
+			}
+
+			return f
+		}
+	case NumbersAsBig:
+		if i, ok := new(big.Int).SetString(n.String(), 10); ok {
+			return i
+		} else if f, _, err := big.ParseFloat(n.String(), 10, 256, big.ToNearestEven); err == nil {
+			return f
+		}
+	}
+
+	return Number{n} // NOTE: Also used if the number is out of range
+}
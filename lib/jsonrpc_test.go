@@ -0,0 +1,127 @@
+package jsonrpc
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+)
+
+func numbersResult() interface{} {
+	return map[string]interface{}{
+		"cpu":   json.Number("75"),
+		"load":  []interface{}{json.Number("0.5"), []interface{}{json.Number("12345678901234567890")}},
+		"disks": []interface{}{map[string]interface{}{"free": json.Number("1024")}},
+	}
+}
+
+func TestConvertNumbers_Number(t *testing.T) {
+	result := convertNumbers(numbersResult(), NumbersAsNumber).(map[string]interface{})
+
+	if v, ok := result["cpu"].(Number); !ok || v.String() != "75" {
+		t.Errorf("Expected Number 75, received %#v", result["cpu"])
+	}
+
+	disk := result["disks"].([]interface{})[0].(map[string]interface{})
+	if v, ok := disk["free"].(Number); !ok || v.String() != "1024" {
+		t.Errorf("Expected nested Number 1024, received %#v", disk["free"])
+	}
+
+	nested := result["load"].([]interface{})[1].([]interface{})
+	if v, ok := nested[0].(Number); !ok || v.String() != "12345678901234567890" {
+		t.Errorf("Expected nested Number, received %#v", nested[0])
+	}
+}
+
+func TestConvertNumbers_Float64(t *testing.T) {
+	result := convertNumbers(numbersResult(), NumbersAsFloat64).(map[string]interface{})
+
+	if v, ok := result["cpu"].(float64); !ok || v != 75 {
+		t.Errorf("Expected float64 75, received %#v", result["cpu"])
+	}
+
+	if v, ok := result["load"].([]interface{})[0].(float64); !ok || v != 0.5 {
+		t.Errorf("Expected float64 0.5, received %#v", result["load"])
+	}
+}
+
+func TestConvertNumbers_Int64(t *testing.T) {
+	result := convertNumbers(numbersResult(), NumbersAsInt64).(map[string]interface{})
+
+	if v, ok := result["cpu"].(int64); !ok || v != 75 {
+		t.Errorf("Expected int64 75, received %#v", result["cpu"])
+	}
+
+	load := result["load"].([]interface{})
+	if v, ok := load[0].(float64); !ok || v != 0.5 {
+		t.Errorf("Expected float64 0.5, received %#v", load[0])
+	}
+
+	if v, ok := load[1].([]interface{})[0].(float64); !ok || v != 12345678901234567890 {
+		t.Errorf("Expected float64 for an integer out of range, received %#v", load[1])
+	}
+
+	for n, expected := range map[string]int64{"1.0": 1, "1e3": 1000, "-2.50e1": -25} {
+		if v, ok := convertNumbers(json.Number(n), NumbersAsInt64).(int64); !ok || v != expected {
+			t.Errorf("Expected int64 %d for %s, received %#v", expected, n, v)
+		}
+	}
+
+	if v, ok := convertNumbers(json.Number("1e19"), NumbersAsInt64).(float64); !ok || v != 1e19 {
+		t.Errorf("Expected float64 for an integral number out of range, received %#v", v)
+	}
+}
+
+func TestConvertNumbers_Big(t *testing.T) {
+	result := convertNumbers(numbersResult(), NumbersAsBig).(map[string]interface{})
+
+	load := result["load"].([]interface{})
+	if v, ok := load[0].(*big.Float); !ok || v.String() != "0.5" {
+		t.Errorf("Expected *big.Float 0.5, received %#v", load[0])
+	}
+
+	if v, ok := load[1].([]interface{})[0].(*big.Int); !ok || v.String() != "12345678901234567890" {
+		t.Errorf("Expected *big.Int, received %#v", load[1])
+	}
+}
+
+type quotaError struct {
+	Used, Limit int
+}
+
+func (err quotaError) Error() string          { return "Quota exceeded" }
+func (err quotaError) ErrorCode() int         { return -32001 }
+func (err quotaError) ErrorData() interface{} { return err }
+
+func TestToInternalError(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected string
+	}{
+		{InvalidParams("expected two params"), `{"code":-32602,"message":"Invalid params","data":"expected two params"}`},
+		{MethodNotFound(nil), `{"code":-32601,"message":"Method not found"}`},
+		{quotaError{10, 5}, `{"code":-32001,"message":"Quota exceeded","data":{"Used":10,"Limit":5}}`},
+	}
+
+	for _, test := range tests {
+		if b, _ := json.Marshal(toInternalError(test.err)); string(b) != test.expected {
+			t.Errorf("Expected `%s`, received `%s`", test.expected, b)
+		}
+	}
+}
+
+func TestError_Data(t *testing.T) {
+	var data struct{ Field string }
+
+	if err := NewErrorWithData(-32000, "Failure", map[string]string{"Field": "name"}).Data(&data); err != nil {
+		t.Errorf("Expected no error, received %s", err.Error())
+		return
+	} else if data.Field != "name" {
+		t.Errorf("Expected `name`, received `%s`", data.Field)
+		return
+	}
+
+	if err := NewError(-32000, "Failure").Data(&data); err == nil {
+		t.Error("Expected an error for missing data, received none")
+		return
+	}
+}
@@ -0,0 +1,234 @@
+package jsonrpc
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/dekelund/jsonrpc/lib/internal"
+)
+
+// Protocol versions selectable with WithVersion.
+const (
+	Version1 = "1.0"
+	Version2 = "2.0"
+)
+
+// Option configures a Client or a Server created with NewClient or
+// NewServer. Options not applicable to the one being created are ignored.
+type Option func(*options)
+
+type options struct {
+	version         string
+	parallelBatches bool
+	nextID          func() ID
+	numbers         NumberMode
+	errors          ErrorMapper
+	panicHook       func(req *Request, recovered interface{}, stack []byte)
+	debug           bool
+
+	maxInFlight      int
+	maxInFlightTotal int
+	sequential       []string
+
+	httpClient   *http.Client
+	checkOrigin  func(req *http.Request) bool
+	framer       Framer
+	maxFrameSize int
+	exitStatus   func() error // NOTE: Set by StartProcess
+}
+
+func newOptions(opts []Option) options {
+	var counter int64
+
+	o := options{
+		version:      Version1,
+		maxInFlight:  1,
+		framer:       Stream,
+		maxFrameSize: internal.DefaultMaxFrameSize,
+		nextID: func() ID {
+			counter++
+			return IntID(counter)
+		},
+	}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
+
+// WithVersion selects the protocol version, Version1 is the default. In
+// Version2 every message carries a "jsonrpc":"2.0" member, and received
+// messages without it are rejected.
+func WithVersion(version string) Option {
+	if version != Version1 && version != Version2 {
+		panic(fmt.Sprintf("jsonrpc: unsupported version %s", version))
+	}
+
+	return func(o *options) {
+		o.version = version
+	}
+}
+
+// WithParallelBatches makes the server handle the calls of a batch in
+// parallel rather than one after another, as far as the limits allow, see
+// WithMaxInFlight and WithMaxInFlightTotal.
+func WithParallelBatches() Option {
+	return func(o *options) {
+		o.parallelBatches = true
+	}
+}
+
+// WithIDGenerator replaces the client's default ID allocation, a counter
+// starting at 1. The generator is never called concurrently, IDs already
+// used by outstanding calls are skipped.
+func WithIDGenerator(next func() ID) Option {
+	return func(o *options) {
+		o.nextID = next
+	}
+}
+
+// WithNumberMode selects how the client represents numbers in call
+// results, NumbersAsNumber is the default. Results decoded by CallInto
+// aren't affected.
+func WithNumberMode(mode NumberMode) Option {
+	return func(o *options) {
+		o.numbers = mode
+	}
+}
+
+// WithErrorMapper makes the server convert handler errors, and the client
+// received errors, using mapper. Use the same mapper, e.g. an ErrorMap, on
+// both sides.
+func WithErrorMapper(mapper ErrorMapper) Option {
+	return func(o *options) {
+		o.errors = mapper
+	}
+}
+
+// WithPanicHook makes the server call hook when a handler panics, with the
+// recovered value and the stack trace. The panic is answered with an
+// internal error regardless.
+func WithPanicHook(hook func(req *Request, recovered interface{}, stack []byte)) Option {
+	return func(o *options) {
+		o.panicHook = hook
+	}
+}
+
+// WithDebug makes the server include the panic and stack trace as data of
+// the internal error sent when a handler panics.
+func WithDebug() Option {
+	return func(o *options) {
+		o.debug = true
+	}
+}
+
+// WithMaxInFlight sets how many handlers the server runs concurrently per
+// connection, 1 is the default and handles calls one at a time, 0 means
+// unlimited. Responses are written as the handlers complete, in any order.
+func WithMaxInFlight(n int) Option {
+	return func(o *options) {
+		o.maxInFlight = n
+	}
+}
+
+// WithMaxInFlightTotal sets how many handlers the server runs concurrently
+// across all its connections, 0 means unlimited which is the default.
+func WithMaxInFlightTotal(n int) Option {
+	return func(o *options) {
+		o.maxInFlightTotal = n
+	}
+}
+
+// WithSequential makes the server handle calls to the given methods one at
+// a time in arrival order, while other calls are handled concurrently. A
+// pattern ending with * matches methods by prefix, e.g. "ledger.*", calls
+// matching the same pattern are handled in sequence, including those of
+// batches.
+func WithSequential(patterns ...string) Option {
+	return func(o *options) {
+		o.sequential = append(o.sequential, patterns...)
+	}
+}
+
+// WithFramer selects how messages are delimited on the connection, Stream
+// is the default. It doesn't apply to the HTTP and WebSocket transports,
+// which delimit messages themselves.
+func WithFramer(framer Framer) Option {
+	return func(o *options) {
+		o.framer = framer
+	}
+}
+
+// WithMaxFrameSize sets the size in bytes of the largest message read
+// from a connection, DefaultMaxFrameSize is the default. A larger message
+// closes the connection, a larger HTTP request body is answered with 413
+// Request Entity Too Large.
+func WithMaxFrameSize(n int) Option {
+	return func(o *options) {
+		o.maxFrameSize = n
+	}
+}
+
+// WithHTTPClient makes a client created with NewHTTPClient send requests
+// using c rather than http.DefaultClient.
+func WithHTTPClient(c *http.Client) Option {
+	return func(o *options) {
+		o.httpClient = c
+	}
+}
+
+// WithCheckOrigin makes ServeWebSocket accept the upgrade requests for which
+// fn returns true. By default only requests without an Origin header, or
+// from the same host, are accepted.
+func WithCheckOrigin(fn func(req *http.Request) bool) Option {
+	return func(o *options) {
+		o.checkOrigin = fn
+	}
+}
+
+// sequence returns the first sequential pattern matching the method, if any.
+func (o options) sequence(method string) (string, bool) {
+	for _, pattern := range o.sequential {
+		if strings.HasSuffix(pattern, "*") && strings.HasPrefix(method, strings.TrimSuffix(pattern, "*")) {
+			return pattern, true
+		} else if pattern == method {
+			return pattern, true
+		}
+	}
+
+	return "", false
+}
+
+func (o options) toError(err error) error {
+	if o.errors == nil {
+		return err
+	}
+
+	return o.errors.ToError(err)
+}
+
+func (o options) fromError(err Error) error {
+	if o.errors == nil {
+		return err
+	}
+
+	return o.errors.FromError(err)
+}
+
+// wireVersion returns the jsonrpc member for the selected version.
+func (o options) wireVersion() string {
+	if o.version == Version2 {
+		return Version2
+	}
+
+	return ""
+}
+
+// notification reports whether the method call is a notification, a call
+// without ID or, in JSON-RPC 1.0, with a null ID.
+func (o options) notification(method internal.Method) bool {
+	return method.ID == nil || o.version != Version2 && method.ID.IsNull()
+}
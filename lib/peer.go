@@ -0,0 +1,75 @@
+package jsonrpc
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	"github.com/dekelund/jsonrpc/lib/internal"
+)
+
+// Peer calls methods on, and serves method calls from, the other end of a
+// single connection. Incoming messages are told apart by whether they are
+// method calls or responses, so both ends may be peers, or one a Client and
+// the other a Server.
+type Peer struct {
+	*Client
+	*Server
+}
+
+// NewPeer returns a peer reading method calls and responses from r and
+// writing method calls and responses to w. Unlike a Server, a peer runs
+// any number of handlers per connection by default, so that handlers can
+// call back into the other end and wait for the calls they cause, see
+// WithMaxInFlight.
+func NewPeer(r io.ReadCloser, w io.WriteCloser, opts ...Option) *Peer {
+	o := newOptions(append([]Option{WithMaxInFlight(0)}, opts...))
+
+	w = &lockedWriter{w: w} // NOTE: Shared by the method and response writers
+	methods, responses := internal.NewFramedMessageReader(r, o.framer, o.maxFrameSize, 10)
+
+	peer := Peer{
+		Client: newClient(responses, internal.NewFramedMethodWriter(w, o.framer, 10), o),
+		Server: newServer(o),
+	}
+
+	peer.Server.intercept = peer.Client.notification
+	peer.serveStream(methods, internal.NewFramedResponseWriter(w, o.framer, 10))
+
+	return &peer
+}
+
+// StopServing stops the client and the server of the peer.
+func (peer *Peer) StopServing(max time.Duration) {
+	wg := sync.WaitGroup{}
+	wg.Add(2)
+
+	go func() {
+		peer.Client.StopServing(max)
+		wg.Done()
+	}()
+
+	go func() {
+		peer.Server.StopServing(max)
+		wg.Done()
+	}()
+
+	wg.Wait()
+}
+
+// lockedWriter serializes the writes of the method and response writers.
+type lockedWriter struct {
+	mutex sync.Mutex
+	w     io.WriteCloser
+}
+
+func (lw *lockedWriter) Write(p []byte) (int, error) {
+	lw.mutex.Lock()
+	defer lw.mutex.Unlock()
+
+	return lw.w.Write(p)
+}
+
+func (lw *lockedWriter) Close() error {
+	return lw.w.Close()
+}
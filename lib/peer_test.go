@@ -0,0 +1,115 @@
+package jsonrpc
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+)
+
+func newTestPeers(opts ...Option) (*Peer, *Peer) {
+	ar, bw := io.Pipe()
+	br, aw := io.Pipe()
+
+	return NewPeer(ar, aw, opts...), NewPeer(br, bw, opts...)
+}
+
+func TestPeer_Call(t *testing.T) {
+	a, b := newTestPeers()
+	defer a.StopServing(time.Second)
+	defer b.StopServing(time.Second)
+
+	a.HandleFunc("system.info", func(req *Request) (interface{}, error) {
+		return "a", nil
+	})
+
+	b.HandleFunc("system.info", func(req *Request) (interface{}, error) {
+		return "b", nil
+	})
+
+	if result, err := a.Call("system.info"); err != nil || result != "b" {
+		t.Errorf("Expected `b`, received %v, %v", result, err)
+		return
+	}
+
+	if result, err := b.Call("system.info"); err != nil || result != "a" {
+		t.Errorf("Expected `a`, received %v, %v", result, err)
+		return
+	}
+}
+
+func TestPeer_Callback(t *testing.T) {
+	a, b := newTestPeers()
+	defer a.StopServing(time.Second)
+	defer b.StopServing(time.Second)
+
+	a.HandleFunc("editor.selection", func(req *Request) (interface{}, error) {
+		mode, err := a.Call("editor.mode") // NOTE: Nested, while b's format handler is running
+		if err != nil {
+			return nil, err
+		}
+
+		return mode.(string) + " text", nil
+	})
+
+	b.HandleFunc("editor.mode", func(req *Request) (interface{}, error) {
+		return "selected", nil
+	})
+
+	b.HandleFunc("format", func(req *Request) (interface{}, error) {
+		selection, err := b.Call("editor.selection")
+		if err != nil {
+			return nil, err
+		}
+
+		return "formatted " + selection.(string), nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if result, err := a.CallContext(ctx, "format"); err != nil || result != "formatted selected text" {
+		t.Errorf("Expected `formatted selected text`, received %v, %v", result, err)
+	}
+}
+
+func TestPeer_Batch(t *testing.T) {
+	a, b := newTestPeers()
+	defer a.StopServing(time.Second)
+	defer b.StopServing(time.Second)
+
+	b.HandleFunc("echo", func(req *Request) (interface{}, error) {
+		return req.Params[0], nil
+	})
+
+	batch := a.Batch()
+	cpu, mem := batch.Call("echo", "cpu"), batch.Call("echo", "mem")
+
+	if err := batch.Send(); err != nil {
+		t.Errorf("Expected no error, received %s", err.Error())
+		return
+	}
+
+	if cpu.Result != "cpu" || mem.Result != "mem" {
+		t.Errorf("Expected `cpu` and `mem`, received %v and %v", cpu.Result, mem.Result)
+	}
+}
+
+func TestPeer_Client(t *testing.T) {
+	pr, cw := io.Pipe()
+	cr, pw := io.Pipe()
+
+	peer := NewPeer(pr, pw)
+	defer peer.StopServing(time.Second)
+
+	peer.HandleFunc("echo", func(req *Request) (interface{}, error) {
+		return req.Params[0], nil
+	})
+
+	client := NewClient(cr, cw)
+	defer client.StopServing(time.Second)
+
+	if result, err := client.Call("echo", "cpu"); err != nil || result != "cpu" {
+		t.Errorf("Expected `cpu`, received %v, %v", result, err)
+	}
+}
@@ -1,16 +1,465 @@
 package jsonrpc
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
+	"net"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/dekelund/jsonrpc/lib/internal"
 )
 
+// Request is the method call passed to a Handler. Params holds positional
+// parameters, NamedParams holds the undecoded object of named parameters.
+type Request struct {
+	Method      string
+	Params      []interface{}
+	NamedParams json.RawMessage
+
+	conn         *conn // NOTE: Nil if the transport can't send notifications
+	notification bool
+	subscription *Subscription // NOTE: Set by Subscribe
+}
+
+// DecodeParams decodes the named parameters object into v, or the
+// positional parameters if the call has no named parameters.
+func (req *Request) DecodeParams(v interface{}) error {
+	if req.NamedParams != nil {
+		return json.Unmarshal(req.NamedParams, v)
+	}
+
+	b, err := json.Marshal(req.Params)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(b, v)
+}
+
+// Handler responds to a single JSON-RPC method call. The returned result
+// is encoded as the response result, unless err is non-nil. Errors of type
+// Error are sent as is, other errors are sent as internal errors.
+type Handler interface {
+	ServeJSONRPC(req *Request) (result interface{}, err error)
+}
+
+// HandlerFunc allows ordinary functions to be used as handlers.
+type HandlerFunc func(req *Request) (result interface{}, err error)
+
+func (fn HandlerFunc) ServeJSONRPC(req *Request) (interface{}, error) {
+	return fn(req)
+}
+
+// ErrServerClosed is returned by Serve and ServeConn after a call to
+// Shutdown or StopServing.
+var ErrServerClosed = errors.New("jsonrpc: Server closed")
+
+// Server dispatches method calls to the registered handlers. A server
+// serves any number of connections, see Serve and ServeConn, which share
+// the handlers.
 type Server struct {
-	r *internal.MethodReader
-	w *internal.ResponseWriter
+	opts options
+
+	mutex    sync.RWMutex
+	handlers map[string]Handler
+
+	total chan bool // NOTE: Nil if unlimited
+
+	// intercept is passed the notifications received by a Peer first, it
+	// returns true if the notification was for the peer's client.
+	intercept func(internal.Method) bool
+
+	connMutex sync.Mutex
+	conns     map[*conn]bool
+	listeners map[net.Listener]bool
+	closed    bool
+}
+
+// NewServer returns a server serving the method calls read from r, the
+// responses are written to w. If both r and w are nil the server has no
+// connection until one is served with Serve or ServeConn.
+func NewServer(r io.ReadCloser, w io.WriteCloser, opts ...Option) *Server {
+	server := newServer(newOptions(opts))
+
+	if r != nil || w != nil {
+		server.serveStream(internal.NewFramedMethodReader(r, server.opts.framer, server.opts.maxFrameSize, 10), internal.NewFramedResponseWriter(w, server.opts.framer, 10))
+	}
+
+	return server
 }
 
-func NewServer(r io.ReadCloser, w io.WriteCloser) *Server {
-	return &Server{internal.NewMethodReader(r, 10), internal.NewResponseWriter(w, 10)}
+func newServer(opts options) *Server {
+	server := Server{
+		opts:     opts,
+		handlers: make(map[string]Handler),
+
+		conns:     make(map[*conn]bool),
+		listeners: make(map[net.Listener]bool),
+	}
+
+	if server.opts.maxInFlightTotal > 0 {
+		server.total = make(chan bool, server.opts.maxInFlightTotal)
+	}
+
+	return &server
+}
+
+// serveStream serves the method calls read by r in the background, unlike
+// ServeConn the connection isn't stopped when the peer closes it.
+func (server *Server) serveStream(r *internal.MethodReader, w *internal.ResponseWriter) {
+	c := newConn(server, r, w)
+	server.track(c)
+
+	go c.serve(false)
+}
+
+// Handle registers the handler for the given method name. Handlers should
+// be registered before the peer starts to send method calls, calls to
+// unknown methods are answered with a method not found error.
+func (server *Server) Handle(name string, handler Handler) {
+	if name == "" {
+		panic("jsonrpc: invalid method name")
+	}
+
+	if handler == nil {
+		panic("jsonrpc: nil handler")
+	}
+
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	if _, ok := server.handlers[name]; ok {
+		panic(fmt.Sprintf("jsonrpc: multiple registrations for %s", name))
+	}
+
+	server.handlers[name] = handler
+}
+
+// HandleFunc registers the handler function for the given method name.
+func (server *Server) HandleFunc(name string, fn func(req *Request) (interface{}, error)) {
+	server.Handle(name, HandlerFunc(fn))
+}
+
+// Serve accepts connections on l and serves each in a go-routine of its
+// own until l fails or the server is shut down, in which case
+// ErrServerClosed is returned. The listener is closed on return.
+func (server *Server) Serve(l net.Listener) error {
+	defer l.Close()
+
+	server.connMutex.Lock()
+	if server.closed {
+		server.connMutex.Unlock()
+		return ErrServerClosed
+	}
+	server.listeners[l] = true
+	server.connMutex.Unlock()
+
+	defer func() {
+		server.connMutex.Lock()
+		delete(server.listeners, l)
+		server.connMutex.Unlock()
+	}()
+
+	for {
+		rwc, err := l.Accept()
+		if err != nil {
+			if server.isClosed() {
+				return ErrServerClosed
+			}
+
+			return err
+		}
+
+		go server.ServeConn(rwc)
+	}
+}
+
+// ServeConn serves the method calls read from rwc and blocks until the
+// peer closes the connection, which is then closed as well.
+func (server *Server) ServeConn(rwc io.ReadWriteCloser) error {
+	return server.serveConn(rwc, server.opts.framer)
+}
+
+func (server *Server) serveConn(rwc io.ReadWriteCloser, framer Framer) error {
+	c := newConn(server, internal.NewFramedMethodReader(rwc, framer, server.opts.maxFrameSize, 10), internal.NewFramedResponseWriter(rwc, framer, 10))
+
+	if !server.track(c) {
+		c.stop(stopTimeout)
+		return ErrServerClosed
+	}
+
+	c.serve(true)
+	<-c.done
+
+	return nil
+}
+
+// Shutdown stops the server gracefully. The listeners are closed, the
+// connections stop reading method calls and are closed once the running
+// handlers have responded. If ctx expires first its error is returned,
+// the remaining connections may be stopped with StopServing.
+func (server *Server) Shutdown(ctx context.Context) error {
+	server.connMutex.Lock()
+	server.closed = true
+
+	for l := range server.listeners {
+		l.Close()
+	}
+
+	conns := make([]*conn, 0, len(server.conns))
+	for c := range server.conns {
+		conns = append(conns, c)
+	}
+	server.connMutex.Unlock()
+
+	for _, c := range conns {
+		c.shutdown()
+	}
+
+	for _, c := range conns {
+		select {
+		case <-c.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
+
+// StopServing closes the listeners and stops every connection without
+// waiting for running handlers, each waiting at most max.
+func (server *Server) StopServing(max time.Duration) {
+	server.connMutex.Lock()
+	server.closed = true
+
+	for l := range server.listeners {
+		l.Close()
+	}
+
+	conns := make([]*conn, 0, len(server.conns))
+	for c := range server.conns {
+		conns = append(conns, c)
+	}
+	server.connMutex.Unlock()
+
+	wg := sync.WaitGroup{}
+	wg.Add(len(conns))
+
+	for _, c := range conns {
+		go func(c *conn) {
+			c.stop(max)
+			wg.Done()
+		}(c)
+	}
+
+	wg.Wait()
+}
+
+// track adds the connection to the server, unless the server is closed.
+func (server *Server) track(c *conn) bool {
+	server.connMutex.Lock()
+	defer server.connMutex.Unlock()
+
+	if server.closed {
+		return false
+	}
+
+	server.conns[c] = true
+
+	return true
+}
+
+func (server *Server) untrack(c *conn) {
+	server.connMutex.Lock()
+	defer server.connMutex.Unlock()
+
+	delete(server.conns, c)
+}
+
+func (server *Server) isClosed() bool {
+	server.connMutex.Lock()
+	defer server.connMutex.Unlock()
+
+	return server.closed
+}
+
+// batchCall collects the responses to the calls of a batch, notifications
+// excluded, and the subscriptions they create.
+type batchCall struct {
+	server    *Server
+	conn      *conn // NOTE: Nil if the transport can't send notifications
+	batch     internal.Batch
+	pending   []int // NOTE: The indexes of the calls handled by run
+	responses []*internal.Response
+	subs      []*Subscription
+	wg        sync.WaitGroup
+}
+
+func (server *Server) newBatchCall(batch internal.Batch, c *conn) *batchCall {
+	b := &batchCall{
+		server:    server,
+		conn:      c,
+		batch:     batch,
+		responses: make([]*internal.Response, len(batch.Methods)),
+		subs:      make([]*Subscription, len(batch.Methods)),
+	}
+
+	for i := range batch.Methods {
+		b.pending = append(b.pending, i)
+	}
+
+	b.wg.Add(len(batch.Methods))
+
+	return b
+}
+
+// run handles the calls of the batch, the caller holds a slot in the
+// semaphores for one call at a time. With WithParallelBatches further calls
+// run concurrently in the slots which are free, each call holds a slot like
+// a single call does.
+func (b *batchCall) run(sems ...chan bool) {
+	next := make(chan int, len(b.pending))
+	for _, i := range b.pending {
+		next <- i
+	}
+	close(next)
+
+	work := func() {
+		for i := range next {
+			b.call(i)
+		}
+	}
+
+	if b.server.opts.parallelBatches {
+		for n := 1; n < len(b.pending) && tryAcquire(sems...); n++ {
+			go func() {
+				defer func() {
+					for _, sem := range sems {
+						release(sem)
+					}
+				}()
+
+				work()
+			}()
+		}
+	}
+
+	work()
+}
+
+// call handles the i:th call of the batch.
+func (b *batchCall) call(i int) {
+	defer b.wg.Done()
+
+	method := b.batch.Methods[i]
+
+	response, sub := b.server.call(method, b.conn)
+	if !b.server.opts.notification(method) {
+		b.responses[i] = &response
+	}
+
+	b.subs[i] = sub
+}
+
+// wait returns the responses and the created subscriptions once all calls
+// of the batch have returned.
+func (b *batchCall) wait() ([]internal.Response, []*Subscription) {
+	b.wg.Wait()
+
+	result := []internal.Response{}
+	for _, e := range b.batch.Invalid {
+		result = append(result, internal.Response{ID: e.ID, Error: errInvalidRequest})
+	}
+
+	for _, response := range b.responses {
+		if response != nil {
+			result = append(result, *response)
+		}
+	}
+
+	created := []*Subscription{}
+	for _, sub := range b.subs {
+		if sub != nil {
+			created = append(created, sub)
+		}
+	}
+
+	return result, created
+}
+
+// call returns the response to the method call, and the subscription
+// created by the handler if any. The connection is nil if the transport
+// can't send notifications.
+func (server *Server) call(method internal.Method, c *conn) (internal.Response, *Subscription) {
+	var id ID
+	if method.ID != nil {
+		id = *method.ID
+	}
+
+	if !server.valid(method) {
+		return internal.Response{ID: id, Error: errInvalidRequest}, nil
+	}
+
+	server.mutex.RLock()
+	handler, ok := server.handlers[method.Method]
+	server.mutex.RUnlock()
+
+	if !ok && c != nil && strings.HasSuffix(method.Method, "_unsubscribe") {
+		handler, ok = HandlerFunc(c.serveUnsubscribe), true
+	}
+
+	if !ok {
+		return internal.Response{ID: id, Error: errMethodNotFound}, nil
+	}
+
+	req := &Request{Method: method.Method, Params: method.Params, NamedParams: method.NamedParams, conn: c, notification: server.opts.notification(method)}
+
+	result, err := server.serveJSONRPC(handler, req)
+	if err != nil {
+		if req.subscription != nil {
+			c.unsubscribe(req.subscription.ID, namespace(method.Method))
+		}
+
+		return internal.Response{ID: id, Error: toInternalError(server.opts.toError(err))}, nil
+	}
+
+	return internal.Response{ID: id, Result: result}, req.subscription
+}
+
+// serveJSONRPC calls the handler, a panic is recovered and returned as an
+// internal error.
+func (server *Server) serveJSONRPC(handler Handler, req *Request) (result interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			stack := debug.Stack()
+
+			if server.opts.panicHook != nil {
+				server.opts.panicHook(req, r, stack)
+			}
+
+			var data interface{}
+			if server.opts.debug {
+				data = fmt.Sprintf("panic: %v\n\n%s", r, stack)
+			}
+
+			result, err = nil, InternalError(data)
+		}
+	}()
+
+	return handler.ServeJSONRPC(req)
+}
+
+func (server *Server) valid(method internal.Method) bool {
+	if method.Method == "" {
+		return false
+	}
+
+	return server.opts.version != Version2 || method.Version == internal.Version2
 }
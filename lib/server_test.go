@@ -0,0 +1,644 @@
+package jsonrpc
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestServer() (*Server, io.WriteCloser, *bufio.Reader) {
+	sr, cw := io.Pipe()
+	cr, sw := io.Pipe()
+
+	return NewServer(sr, sw), cw, bufio.NewReader(cr)
+}
+
+func TestServer_Handle(t *testing.T) {
+	server, w, r := newTestServer()
+	defer server.StopServing(time.Second)
+
+	server.HandleFunc("system.info", func(req *Request) (interface{}, error) {
+		return []interface{}{75, req.Params[1]}, nil
+	})
+
+	io.WriteString(w, `{"id":1,"method":"system.info","params":["cpu","mem"]}`)
+
+	expectedMSG := `{"id":1,"result":[75,"mem"]}` + "\n"
+	if msg, _ := r.ReadString('\n'); msg != expectedMSG {
+		t.Errorf("Expected `%s`, received `%s`", expectedMSG, msg)
+		return
+	}
+}
+
+func TestServer_HandleMethodNotFound(t *testing.T) {
+	server, w, r := newTestServer()
+	defer server.StopServing(time.Second)
+
+	io.WriteString(w, `{"id":1,"method":"system.info","params":[]}`)
+
+	expectedMSG := `{"id":1,"error":{"code":-32601,"message":"Method not found"}}` + "\n"
+	if msg, _ := r.ReadString('\n'); msg != expectedMSG {
+		t.Errorf("Expected `%s`, received `%s`", expectedMSG, msg)
+		return
+	}
+}
+
+func TestServer_HandleError(t *testing.T) {
+	server, w, r := newTestServer()
+	defer server.StopServing(time.Second)
+
+	server.HandleFunc("system.login", func(req *Request) (interface{}, error) {
+		return nil, NewError(-32000, "Access denied")
+	})
+
+	server.HandleFunc("system.info", func(req *Request) (interface{}, error) {
+		return nil, errors.New("Disk failure")
+	})
+
+	io.WriteString(w, `{"id":1,"method":"system.login","params":["root"]}`)
+	io.WriteString(w, `{"id":2,"method":"system.info","params":[]}`)
+
+	expectedMSG := `{"id":1,"error":{"code":-32000,"message":"Access denied"}}` + "\n"
+	if msg, _ := r.ReadString('\n'); msg != expectedMSG {
+		t.Errorf("Expected `%s`, received `%s`", expectedMSG, msg)
+		return
+	}
+
+	expectedMSG = `{"id":2,"error":{"code":-32603,"message":"Disk failure"}}` + "\n"
+	if msg, _ := r.ReadString('\n'); msg != expectedMSG {
+		t.Errorf("Expected `%s`, received `%s`", expectedMSG, msg)
+		return
+	}
+}
+
+func TestServer_HandleNotification(t *testing.T) {
+	server, w, r := newTestServer()
+	defer server.StopServing(time.Second)
+
+	called := make(chan string, 1)
+	server.HandleFunc("system.log", func(req *Request) (interface{}, error) {
+		called <- req.Params[0].(string)
+		return nil, nil
+	})
+
+	server.HandleFunc("system.info", func(req *Request) (interface{}, error) {
+		return "ok", nil
+	})
+
+	io.WriteString(w, `{"method":"system.log","params":["hello"]}`)
+	io.WriteString(w, `{"id":2,"method":"system.info","params":[]}`)
+
+	if msg := <-called; msg != "hello" {
+		t.Errorf("Expected `hello`, received `%s`", msg)
+		return
+	}
+
+	expectedMSG := `{"id":2,"result":"ok"}` + "\n"
+	if msg, _ := r.ReadString('\n'); msg != expectedMSG {
+		t.Errorf("Expected `%s`, received `%s`", expectedMSG, msg)
+		return
+	}
+}
+
+func TestServer_HandleTwice(t *testing.T) {
+	server, _, _ := newTestServer()
+	defer server.StopServing(0)
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("Expected panic from Handle, panic didn't happen")
+		}
+	}()
+
+	fn := func(req *Request) (interface{}, error) { return nil, nil }
+
+	server.HandleFunc("system.info", fn)
+	server.HandleFunc("system.info", fn)
+}
+
+func TestServer_InvalidRequest(t *testing.T) {
+	server, w, r := newTestServer()
+	defer server.StopServing(time.Second)
+
+	io.WriteString(w, `{"id":1,"params":[]}`)
+	io.WriteString(w, `{"id":2,"method":"system.info","params":"cpu"}`)
+	io.WriteString(w, `{"id":3,"method":}`)
+	w.Close()
+
+	for _, expectedMSG := range []string{
+		`{"id":1,"error":{"code":-32600,"message":"Invalid Request"}}`,
+		`{"id":2,"error":{"code":-32600,"message":"Invalid Request"}}`,
+		`{"id":null,"error":{"code":-32700,"message":"Parse error"}}`,
+	} {
+		if msg, _ := r.ReadString('\n'); msg != expectedMSG+"\n" {
+			t.Errorf("Expected `%s`, received `%s`", expectedMSG, msg)
+			return
+		}
+	}
+}
+
+func TestServer_Version2(t *testing.T) {
+	sr, cw := io.Pipe()
+	cr, sw := io.Pipe()
+	r := bufio.NewReader(cr)
+
+	server := NewServer(sr, sw, WithVersion(Version2))
+	defer server.StopServing(time.Second)
+
+	server.HandleFunc("system.info", func(req *Request) (interface{}, error) {
+		return nil, nil
+	})
+
+	io.WriteString(cw, `{"jsonrpc":"2.0","id":1,"method":"system.info","params":[]}`)
+	io.WriteString(cw, `{"id":2,"method":"system.info","params":[]}`)
+
+	for _, expectedMSG := range []string{
+		`{"jsonrpc":"2.0","id":1,"result":null}`,
+		`{"jsonrpc":"2.0","id":2,"error":{"code":-32600,"message":"Invalid Request"}}`,
+	} {
+		if msg, _ := r.ReadString('\n'); msg != expectedMSG+"\n" {
+			t.Errorf("Expected `%s`, received `%s`", expectedMSG, msg)
+			return
+		}
+	}
+}
+
+func TestServer_StringID(t *testing.T) {
+	server, w, r := newTestServer()
+	defer server.StopServing(time.Second)
+
+	server.HandleFunc("system.info", func(req *Request) (interface{}, error) {
+		return "ok", nil
+	})
+
+	io.WriteString(w, `{"id":"abc-1","method":"system.info","params":[]}`)
+	io.WriteString(w, `{"id":null,"method":"system.info","params":[]}`) // NOTE: A notification in JSON-RPC 1.0
+	io.WriteString(w, `{"id":"\u0061","method":"system.info","params":[]}`)
+
+	for _, expectedMSG := range []string{
+		`{"id":"abc-1","result":"ok"}`,
+		`{"id":"a","result":"ok"}`,
+	} {
+		if msg, _ := r.ReadString('\n'); msg != expectedMSG+"\n" {
+			t.Errorf("Expected `%s`, received `%s`", expectedMSG, msg)
+			return
+		}
+	}
+}
+
+func TestServer_NamedParams(t *testing.T) {
+	server, w, r := newTestServer()
+	defer server.StopServing(time.Second)
+
+	server.HandleFunc("system.login", func(req *Request) (interface{}, error) {
+		var params struct {
+			User string `json:"user"`
+		}
+
+		if err := req.DecodeParams(&params); err != nil {
+			return nil, err
+		}
+
+		return params.User, nil
+	})
+
+	io.WriteString(w, `{"id":1,"method":"system.login","params":{"user":"root"}}`)
+
+	expectedMSG := `{"id":1,"result":"root"}` + "\n"
+	if msg, _ := r.ReadString('\n'); msg != expectedMSG {
+		t.Errorf("Expected `%s`, received `%s`", expectedMSG, msg)
+		return
+	}
+}
+
+func TestServer_Batch(t *testing.T) {
+	sr, cw := io.Pipe()
+	cr, sw := io.Pipe()
+	r := bufio.NewReader(cr)
+
+	server := NewServer(sr, sw, WithParallelBatches())
+	defer server.StopServing(time.Second)
+
+	server.HandleFunc("system.info", func(req *Request) (interface{}, error) {
+		return req.Params[0], nil
+	})
+
+	io.WriteString(cw, `[{"id":1,"method":"system.info","params":["cpu"]},{"method":"system.info","params":["log"]},{"id":2,"method":1},{"id":3,"method":"system.info","params":["mem"]}]`)
+	io.WriteString(cw, `[{"method":"system.info","params":["log"]}]`)
+	io.WriteString(cw, `[]`)
+
+	for _, expectedMSG := range []string{
+		`[{"id":2,"error":{"code":-32600,"message":"Invalid Request"}},{"id":1,"result":"cpu"},{"id":3,"result":"mem"}]`,
+		`{"id":null,"error":{"code":-32600,"message":"Invalid Request"}}`,
+	} {
+		if msg, _ := r.ReadString('\n'); msg != expectedMSG+"\n" {
+			t.Errorf("Expected `%s`, received `%s`", expectedMSG, msg)
+			return
+		}
+	}
+}
+
+func TestServer_HandlerPanic(t *testing.T) {
+	sr, cw := io.Pipe()
+	cr, sw := io.Pipe()
+	r := bufio.NewReader(cr)
+
+	recovered := make(chan interface{}, 1)
+	server := NewServer(sr, sw, WithDebug(), WithPanicHook(func(req *Request, v interface{}, stack []byte) {
+		recovered <- v
+	}))
+	defer server.StopServing(time.Second)
+
+	server.HandleFunc("system.crash", func(req *Request) (interface{}, error) {
+		panic("out of cheese")
+	})
+
+	server.HandleFunc("system.info", func(req *Request) (interface{}, error) {
+		return "ok", nil
+	})
+
+	io.WriteString(cw, `{"id":1,"method":"system.crash","params":[]}`)
+
+	msg, _ := r.ReadString('\n')
+	if !strings.HasPrefix(msg, `{"id":1,"error":{"code":-32603,"message":"Internal error","data":"panic: out of cheese\n\ngoroutine `) {
+		t.Errorf("Expected internal error with stack, received `%s`", msg)
+		return
+	}
+
+	if v := <-recovered; v != "out of cheese" {
+		t.Errorf("Expected hook to receive `out of cheese`, received %#v", v)
+		return
+	}
+
+	io.WriteString(cw, `{"id":2,"method":"system.info","params":[]}`)
+
+	expectedMSG := `{"id":2,"result":"ok"}` + "\n"
+	if msg, _ := r.ReadString('\n'); msg != expectedMSG {
+		t.Errorf("Expected `%s`, received `%s`", expectedMSG, msg)
+		return
+	}
+}
+
+func TestServer_MaxInFlight(t *testing.T) {
+	sr, cw := io.Pipe()
+	cr, sw := io.Pipe()
+	r := bufio.NewReader(cr)
+
+	server := NewServer(sr, sw, WithMaxInFlight(2))
+	defer server.StopServing(time.Second)
+
+	unblock := make(chan bool)
+	running := make(chan string, 3)
+
+	server.HandleFunc("system.wait", func(req *Request) (interface{}, error) {
+		running <- "wait"
+		<-unblock
+		return "waited", nil
+	})
+
+	server.HandleFunc("system.info", func(req *Request) (interface{}, error) {
+		running <- "info"
+		return "ok", nil
+	})
+
+	io.WriteString(cw, `{"id":1,"method":"system.wait","params":[]}`)
+	io.WriteString(cw, `{"id":2,"method":"system.info","params":[]}`)
+	io.WriteString(cw, `{"id":3,"method":"system.wait","params":[]}`)
+
+	// NOTE: The slow call doesn't stall the next one
+	expectedMSG := `{"id":2,"result":"ok"}` + "\n"
+	if msg, _ := r.ReadString('\n'); msg != expectedMSG {
+		t.Errorf("Expected `%s`, received `%s`", expectedMSG, msg)
+		return
+	}
+
+	<-running
+	<-running
+	<-running
+
+	close(unblock)
+
+	for i := 0; i < 2; i++ {
+		if msg, _ := r.ReadString('\n'); !strings.HasSuffix(msg, `"result":"waited"}`+"\n") {
+			t.Errorf("Expected waited result, received `%s`", msg)
+			return
+		}
+	}
+}
+
+func TestServer_MaxInFlightTotal(t *testing.T) {
+	sr, cw := io.Pipe()
+	cr, sw := io.Pipe()
+	r := bufio.NewReader(cr)
+
+	server := NewServer(sr, sw, WithMaxInFlight(0), WithMaxInFlightTotal(1))
+	defer server.StopServing(time.Second)
+
+	var mutex sync.Mutex
+	concurrent, max := 0, 0
+
+	server.HandleFunc("system.info", func(req *Request) (interface{}, error) {
+		mutex.Lock()
+		if concurrent++; concurrent > max {
+			max = concurrent
+		}
+		mutex.Unlock()
+
+		time.Sleep(time.Millisecond)
+
+		mutex.Lock()
+		concurrent--
+		mutex.Unlock()
+
+		return "ok", nil
+	})
+
+	go func() {
+		for i := 0; i < 5; i++ {
+			io.WriteString(cw, `{"id":1,"method":"system.info","params":[]}`)
+		}
+	}()
+
+	for i := 0; i < 5; i++ {
+		r.ReadString('\n')
+	}
+
+	if max != 1 {
+		t.Errorf("Expected at most one handler at a time, received %d", max)
+	}
+}
+
+func TestServer_MaxInFlightTotalParallelBatch(t *testing.T) {
+	sr, cw := io.Pipe()
+	cr, sw := io.Pipe()
+	r := bufio.NewReader(cr)
+
+	server := NewServer(sr, sw, WithMaxInFlight(0), WithMaxInFlightTotal(1), WithParallelBatches())
+	defer server.StopServing(time.Second)
+
+	var mutex sync.Mutex
+	concurrent, max := 0, 0
+
+	server.HandleFunc("system.info", func(req *Request) (interface{}, error) {
+		mutex.Lock()
+		if concurrent++; concurrent > max {
+			max = concurrent
+		}
+		mutex.Unlock()
+
+		time.Sleep(time.Millisecond)
+
+		mutex.Lock()
+		concurrent--
+		mutex.Unlock()
+
+		return "ok", nil
+	})
+
+	io.WriteString(cw, `[{"id":1,"method":"system.info","params":[]},{"id":2,"method":"system.info","params":[]},{"id":3,"method":"system.info","params":[]}]`)
+	r.ReadString('\n')
+
+	if max != 1 {
+		t.Errorf("Expected at most one handler at a time, received %d", max)
+	}
+}
+
+func TestServer_Sequential(t *testing.T) {
+	sr, cw := io.Pipe()
+	cr, sw := io.Pipe()
+	r := bufio.NewReader(cr)
+
+	server := NewServer(sr, sw, WithMaxInFlight(0), WithSequential("ledger.*"))
+	defer server.StopServing(time.Second)
+
+	var mutex sync.Mutex
+	var ledger []interface{}
+
+	unblock := make(chan bool)
+	server.HandleFunc("ledger.append", func(req *Request) (interface{}, error) {
+		if req.Params[0] == "first" {
+			<-unblock // NOTE: Later entries must wait
+		}
+
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		ledger = append(ledger, req.Params[0])
+		return len(ledger), nil
+	})
+
+	server.HandleFunc("system.info", func(req *Request) (interface{}, error) {
+		return "ok", nil
+	})
+
+	io.WriteString(cw, `{"id":1,"method":"ledger.append","params":["first"]}`)
+	io.WriteString(cw, `{"id":2,"method":"ledger.append","params":["second"]}`)
+	io.WriteString(cw, `{"id":3,"method":"system.info","params":[]}`)
+
+	expectedMSG := `{"id":3,"result":"ok"}` + "\n"
+	if msg, _ := r.ReadString('\n'); msg != expectedMSG {
+		t.Errorf("Expected `%s`, received `%s`", expectedMSG, msg)
+		return
+	}
+
+	close(unblock)
+
+	for _, expectedMSG := range []string{`{"id":1,"result":1}`, `{"id":2,"result":2}`} {
+		if msg, _ := r.ReadString('\n'); msg != expectedMSG+"\n" {
+			t.Errorf("Expected `%s`, received `%s`", expectedMSG, msg)
+			return
+		}
+	}
+}
+
+func TestServer_SequentialBatch(t *testing.T) {
+	sr, cw := io.Pipe()
+	cr, sw := io.Pipe()
+	r := bufio.NewReader(cr)
+
+	server := NewServer(sr, sw, WithMaxInFlight(0), WithSequential("ledger.*"))
+	defer server.StopServing(time.Second)
+
+	var mutex sync.Mutex
+	var ledger []interface{}
+
+	unblock := make(chan bool)
+	server.HandleFunc("ledger.append", func(req *Request) (interface{}, error) {
+		if req.Params[0] == "first" {
+			<-unblock // NOTE: The batched entry must wait
+		}
+
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		ledger = append(ledger, req.Params[0])
+		return len(ledger), nil
+	})
+
+	server.HandleFunc("system.info", func(req *Request) (interface{}, error) {
+		return "ok", nil
+	})
+
+	io.WriteString(cw, `{"id":1,"method":"ledger.append","params":["first"]}`)
+	io.WriteString(cw, `[{"id":2,"method":"ledger.append","params":["second"]}]`)
+	io.WriteString(cw, `{"id":3,"method":"system.info","params":[]}`)
+
+	expectedMSG := `{"id":3,"result":"ok"}` + "\n"
+	if msg, _ := r.ReadString('\n'); msg != expectedMSG {
+		t.Errorf("Expected `%s`, received `%s`", expectedMSG, msg)
+		return
+	}
+
+	close(unblock)
+
+	for _, expectedMSG := range []string{`{"id":1,"result":1}`, `[{"id":2,"result":2}]`} {
+		if msg, _ := r.ReadString('\n'); msg != expectedMSG+"\n" {
+			t.Errorf("Expected `%s`, received `%s`", expectedMSG, msg)
+			return
+		}
+	}
+}
+
+func TestServer_Serve(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Errorf("Expected no error, received %s", err.Error())
+		return
+	}
+
+	server := NewServer(nil, nil)
+	defer server.StopServing(time.Second)
+
+	server.HandleFunc("system.info", func(req *Request) (interface{}, error) {
+		return req.Params[0], nil
+	})
+
+	go server.Serve(l)
+
+	for _, param := range []string{"cpu", "mem"} {
+		conn, err := net.Dial("tcp", l.Addr().String())
+		if err != nil {
+			t.Errorf("Expected no error, received %s", err.Error())
+			return
+		}
+		defer conn.Close()
+
+		io.WriteString(conn, `{"id":1,"method":"system.info","params":["`+param+`"]}`)
+
+		expectedMSG := `{"id":1,"result":"` + param + `"}` + "\n"
+		if msg, _ := bufio.NewReader(conn).ReadString('\n'); msg != expectedMSG {
+			t.Errorf("Expected `%s`, received `%s`", expectedMSG, msg)
+			return
+		}
+	}
+}
+
+func TestServer_Shutdown(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Errorf("Expected no error, received %s", err.Error())
+		return
+	}
+
+	server := NewServer(nil, nil)
+
+	started, unblock := make(chan bool), make(chan bool)
+	server.HandleFunc("system.info", func(req *Request) (interface{}, error) {
+		close(started)
+		<-unblock
+		return "ok", nil
+	})
+
+	served := make(chan error, 1)
+	go func() { served <- server.Serve(l) }()
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Errorf("Expected no error, received %s", err.Error())
+		return
+	}
+	defer conn.Close()
+
+	io.WriteString(conn, `{"id":1,"method":"system.info","params":[]}`)
+	<-started
+
+	shutdown := make(chan error, 1)
+	go func() { shutdown <- server.Shutdown(context.Background()) }()
+
+	if err := <-served; err != ErrServerClosed {
+		t.Errorf("Expected ErrServerClosed, received %v", err)
+		return
+	}
+
+	select {
+	case <-shutdown:
+		t.Error("Expected Shutdown to wait for the running handler")
+		return
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	close(unblock)
+
+	expectedMSG := `{"id":1,"result":"ok"}` + "\n"
+	if msg, _ := bufio.NewReader(conn).ReadString('\n'); msg != expectedMSG {
+		t.Errorf("Expected `%s`, received `%s`", expectedMSG, msg)
+		return
+	}
+
+	if err := <-shutdown; err != nil {
+		t.Errorf("Expected no error, received %s", err.Error())
+	}
+}
+
+func TestServer_ShutdownTimeout(t *testing.T) {
+	server, w, _ := newTestServer()
+	defer server.StopServing(0)
+
+	started, unblock := make(chan bool), make(chan bool)
+	defer close(unblock)
+
+	server.HandleFunc("system.info", func(req *Request) (interface{}, error) {
+		close(started)
+		<-unblock
+		return "ok", nil
+	})
+
+	io.WriteString(w, `{"id":1,"method":"system.info","params":[]}`)
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := server.Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Expected a deadline exceeded error, received %v", err)
+	}
+}
+
+func TestServer_Framer(t *testing.T) {
+	sr, cw := io.Pipe()
+	cr, sw := io.Pipe()
+	r := bufio.NewReader(cr)
+
+	server := NewServer(sr, sw, WithFramer(ContentLength))
+	defer server.StopServing(time.Second)
+
+	server.HandleFunc("system.info", func(req *Request) (interface{}, error) {
+		return "ok", nil
+	})
+
+	msg := `{"id":1,"method":"system.info","params":[]}`
+	io.WriteString(cw, "Content-Length: "+strconv.Itoa(len(msg))+"\r\nContent-Type: application/vscode-jsonrpc; charset=utf-8\r\n\r\n"+msg)
+
+	expectedMSG := "Content-Length: 22\r\n\r\n" + `{"id":1,"result":"ok"}`
+	b := make([]byte, len(expectedMSG))
+	if io.ReadFull(r, b); string(b) != expectedMSG {
+		t.Errorf("Expected `%s`, received `%s`", expectedMSG, b)
+	}
+}
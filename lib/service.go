@@ -0,0 +1,169 @@
+package jsonrpc
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+)
+
+var typeOfError = reflect.TypeOf((*error)(nil)).Elem()
+
+// methodHandler calls an exported method of a registered receiver. The
+// positional parameters are decoded into the method's arguments, named
+// parameters into the argument of methods taking one argument. A trailing
+// error return value is sent as the response error and the remaining return
+// values as the result, an array if there are more than one.
+type methodHandler struct {
+	fn reflect.Value
+}
+
+// Register publishes every exported method of rcvr as a method named
+// "Type.Method", where Type is the name of the receiver's concrete type.
+func (server *Server) Register(rcvr interface{}) error {
+	return server.RegisterName(reflect.Indirect(reflect.ValueOf(rcvr)).Type().Name(), rcvr)
+}
+
+// RegisterName is like Register but uses the provided name instead of the
+// receiver's type name.
+func (server *Server) RegisterName(name string, rcvr interface{}) error {
+	value := reflect.ValueOf(rcvr)
+
+	if name == "" {
+		return errors.New("jsonrpc: no service name for type " + value.Type().String())
+	}
+
+	handlers := make(map[string]Handler)
+	for i := 0; i < value.NumMethod(); i++ {
+		if value.Type().Method(i).PkgPath != "" {
+			continue // Unexported
+		}
+
+		handlers[name+"."+value.Type().Method(i).Name] = &methodHandler{value.Method(i)}
+	}
+
+	if len(handlers) == 0 {
+		return errors.New("jsonrpc: type " + value.Type().String() + " has no exported methods")
+	}
+
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	for method := range handlers {
+		if _, ok := server.handlers[method]; ok {
+			return errors.New("jsonrpc: multiple registrations for " + method)
+		}
+	}
+
+	for method, handler := range handlers {
+		server.handlers[method] = handler
+	}
+
+	return nil
+}
+
+func (h *methodHandler) ServeJSONRPC(req *Request) (interface{}, error) {
+	var args []reflect.Value
+	var err error
+
+	if req.NamedParams != nil {
+		args, err = h.namedArgs(req.NamedParams)
+	} else {
+		args, err = h.args(req.Params)
+	}
+
+	if err != nil {
+		return nil, Error{errInvalidParams}
+	}
+
+	return h.result(h.fn.Call(args))
+}
+
+func (h *methodHandler) args(params []interface{}) ([]reflect.Value, error) {
+	typ := h.fn.Type()
+
+	if typ.IsVariadic() && len(params) < typ.NumIn()-1 {
+		return nil, errors.New("Too few parameters")
+	} else if !typ.IsVariadic() && len(params) != typ.NumIn() {
+		return nil, errors.New("Wrong number of parameters")
+	}
+
+	args := make([]reflect.Value, len(params))
+	for i, param := range params {
+		var argType reflect.Type
+		if typ.IsVariadic() && i >= typ.NumIn()-1 {
+			argType = typ.In(typ.NumIn() - 1).Elem()
+		} else {
+			argType = typ.In(i)
+		}
+
+		arg, err := decodeParam(param, argType)
+		if err != nil {
+			return nil, err
+		}
+
+		args[i] = arg
+	}
+
+	return args, nil
+}
+
+func (h *methodHandler) namedArgs(params json.RawMessage) ([]reflect.Value, error) {
+	if typ := h.fn.Type(); typ.NumIn() != 1 || typ.IsVariadic() {
+		return nil, errors.New("Named parameters requires a single argument")
+	}
+
+	arg := reflect.New(h.fn.Type().In(0))
+	if err := json.Unmarshal(params, arg.Interface()); err != nil {
+		return nil, err
+	}
+
+	return []reflect.Value{arg.Elem()}, nil
+}
+
+func (h *methodHandler) result(out []reflect.Value) (interface{}, error) {
+	if n := len(out); n > 0 && h.fn.Type().Out(n-1) == typeOfError {
+		if err := out[n-1]; !err.IsNil() {
+			return nil, err.Interface().(error)
+		}
+
+		out = out[:n-1]
+	}
+
+	switch len(out) {
+	case 0:
+		return nil, nil
+	case 1:
+		return out[0].Interface(), nil
+	}
+
+	results := make([]interface{}, len(out))
+	for i, v := range out {
+		results[i] = v.Interface()
+	}
+
+	return results, nil
+}
+
+// decodeParam converts a decoded parameter into a value of type typ by
+// encoding it back to JSON, unless it's directly assignable.
+func decodeParam(param interface{}, typ reflect.Type) (reflect.Value, error) {
+	if param == nil {
+		return reflect.Zero(typ), nil
+	}
+
+	if v := reflect.ValueOf(param); v.Type().AssignableTo(typ) {
+		return v, nil
+	}
+
+	b, err := json.Marshal(param)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+
+	arg := reflect.New(typ)
+	if err := json.Unmarshal(b, arg.Interface()); err != nil {
+		return reflect.Value{}, err
+	}
+
+	return arg.Elem(), nil
+}
@@ -0,0 +1,105 @@
+package jsonrpc
+
+import (
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+type Arith struct{}
+
+type Operands struct {
+	A, B int
+}
+
+func (Arith) Add(a, b int) int {
+	return a + b
+}
+
+func (Arith) Div(ops Operands) (float64, error) {
+	if ops.B == 0 {
+		return 0, errors.New("Divide by zero")
+	}
+
+	return float64(ops.A) / float64(ops.B), nil
+}
+
+func (Arith) DivMod(a, b int) (int, int) {
+	return a / b, a % b
+}
+
+func (Arith) Sum(values ...int) (sum int) {
+	for _, v := range values {
+		sum += v
+	}
+
+	return
+}
+
+func (Arith) Reset() {}
+
+func TestServer_Register(t *testing.T) {
+	server, w, r := newTestServer()
+	defer server.StopServing(time.Second)
+
+	if err := server.Register(&Arith{}); err != nil {
+		t.Errorf("Expected no error, received %s", err.Error())
+		return
+	}
+
+	tests := []struct {
+		call     string
+		expected string
+	}{
+		{`{"id":1,"method":"Arith.Add","params":[1,2]}`, `{"id":1,"result":3}`},
+		{`{"id":2,"method":"Arith.Div","params":[{"A":3,"B":2}]}`, `{"id":2,"result":1.5}`},
+		{`{"id":3,"method":"Arith.Div","params":[{"A":3,"B":0}]}`, `{"id":3,"error":{"code":-32603,"message":"Divide by zero"}}`},
+		{`{"id":4,"method":"Arith.DivMod","params":[7,2]}`, `{"id":4,"result":[3,1]}`},
+		{`{"id":5,"method":"Arith.Sum","params":[1,2,3,4]}`, `{"id":5,"result":10}`},
+		{`{"id":6,"method":"Arith.Reset","params":[]}`, `{"id":6,"result":null}`},
+		{`{"id":7,"method":"Arith.Add","params":[1]}`, `{"id":7,"error":{"code":-32602,"message":"Invalid params"}}`},
+		{`{"id":8,"method":"Arith.Add","params":[1,"2"]}`, `{"id":8,"error":{"code":-32602,"message":"Invalid params"}}`},
+		{`{"id":9,"method":"Arith.Div","params":{"A":1,"B":4}}`, `{"id":9,"result":0.25}`},
+		{`{"id":10,"method":"Arith.Add","params":{"a":1,"b":4}}`, `{"id":10,"error":{"code":-32602,"message":"Invalid params"}}`},
+	}
+
+	for _, test := range tests {
+		io.WriteString(w, test.call)
+
+		if msg, _ := r.ReadString('\n'); msg != test.expected+"\n" {
+			t.Errorf("Expected `%s`, received `%s`", test.expected, msg)
+			return
+		}
+	}
+}
+
+func TestServer_RegisterTwice(t *testing.T) {
+	server, _, _ := newTestServer()
+	defer server.StopServing(0)
+
+	if err := server.Register(Arith{}); err != nil {
+		t.Errorf("Expected no error, received %s", err.Error())
+		return
+	}
+
+	if err := server.Register(Arith{}); err == nil {
+		t.Error("Expected an error, received none")
+		return
+	}
+
+	if err := server.RegisterName("Math", Arith{}); err != nil {
+		t.Errorf("Expected no error, received %s", err.Error())
+		return
+	}
+}
+
+func TestServer_RegisterNoMethods(t *testing.T) {
+	server, _, _ := newTestServer()
+	defer server.StopServing(0)
+
+	if err := server.Register(Operands{}); err == nil {
+		t.Error("Expected an error, received none")
+		return
+	}
+}
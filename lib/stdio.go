@@ -0,0 +1,113 @@
+package jsonrpc
+
+import (
+	"io"
+	"os"
+	"os/exec"
+)
+
+// StartProcess starts cmd and returns a client calling methods over its
+// standard input and output. When the process exits, pending and later
+// calls fail with an EOF error, or with the exit error, e.g. "exit status
+// 1", if the process failed. StopServing closes the standard input of the
+// process, which is then expected to exit.
+func StartProcess(cmd *exec.Cmd, opts ...Option) (*Client, error) {
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	// NOTE: Not StdoutPipe, Wait would close it before the output is read
+	stdout, w, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+
+	cmd.Stdout = w
+
+	if err := cmd.Start(); err != nil {
+		stdout.Close()
+		w.Close()
+
+		return nil, err
+	}
+
+	w.Close() // NOTE: Held by the process, stdout reads EOF once it exits
+
+	p := &process{stdout: stdout, exited: make(chan bool)}
+
+	go func() {
+		p.err = cmd.Wait()
+		close(p.exited)
+	}()
+
+	client := NewClient(p, stdin, append(opts[:len(opts):len(opts)], withExitStatus(p.exitStatus))...)
+	client.closer = stdin
+
+	return client, nil
+}
+
+// process reads the standard output of a started process.
+type process struct {
+	stdout *os.File
+	exited chan bool
+	err    error // NOTE: Set before exited is closed
+}
+
+// Read reads the output of the process, EOF is returned once it has
+// exited so that its exit status is known.
+func (p *process) Read(b []byte) (int, error) {
+	n, err := p.stdout.Read(b)
+	if err == io.EOF {
+		<-p.exited
+	}
+
+	return n, err
+}
+
+func (p *process) Close() error {
+	return p.stdout.Close()
+}
+
+// exitStatus returns the error of cmd.Wait, nil until the process has
+// exited.
+func (p *process) exitStatus() error {
+	select {
+	case <-p.exited:
+		return p.err
+	default:
+		return nil
+	}
+}
+
+// withExitStatus makes the client fail pending calls with the error
+// returned by fn, if any, rather than EOF.
+func withExitStatus(fn func() error) Option {
+	return func(o *options) {
+		o.exitStatus = fn
+	}
+}
+
+// ServeStdio serves the method calls read from the standard input, the
+// responses are written to the standard output. It blocks until the
+// standard input is closed, the standard output is left open.
+func (server *Server) ServeStdio() error {
+	return server.ServeConn(stdio{})
+}
+
+// stdio reads the standard input and writes the standard output.
+type stdio struct{}
+
+func (stdio) Read(p []byte) (int, error) {
+	return os.Stdin.Read(p)
+}
+
+func (stdio) Write(p []byte) (int, error) {
+	return os.Stdout.Write(p)
+}
+
+// Close closes the standard input only, the standard output is shared by
+// the whole process.
+func (stdio) Close() error {
+	return os.Stdin.Close()
+}
@@ -0,0 +1,77 @@
+package jsonrpc
+
+import (
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+// TestStdioProcess isn't a real test, it's the process started by the
+// StartProcess tests.
+func TestStdioProcess(t *testing.T) {
+	if os.Getenv("JSONRPC_TEST_PROCESS") != "1" {
+		return
+	}
+
+	server := NewServer(nil, nil, WithMaxInFlight(0))
+
+	server.HandleFunc("system.info", func(req *Request) (interface{}, error) {
+		return req.Params[0], nil
+	})
+
+	server.HandleFunc("system.exit", func(req *Request) (interface{}, error) {
+		os.Exit(1)
+		return nil, nil
+	})
+
+	server.ServeStdio()
+	os.Exit(0) // NOTE: Keep the test framework from writing to stdout
+}
+
+func startTestProcess() (*Client, error) {
+	cmd := exec.Command(os.Args[0], "-test.run=^TestStdioProcess$")
+	cmd.Env = append(os.Environ(), "JSONRPC_TEST_PROCESS=1")
+
+	return StartProcess(cmd)
+}
+
+func TestStartProcess(t *testing.T) {
+	client, err := startTestProcess()
+	if err != nil {
+		t.Errorf("Expected no error, received %s", err.Error())
+		return
+	}
+	defer client.StopServing(time.Second)
+
+	for _, param := range []string{"cpu", "mem"} {
+		if result, err := client.Call("system.info", param); err != nil || result != param {
+			t.Errorf("Expected `%s`, received %v, %v", param, result, err)
+			return
+		}
+	}
+}
+
+func TestStartProcess_Exit(t *testing.T) {
+	client, err := startTestProcess()
+	if err != nil {
+		t.Errorf("Expected no error, received %s", err.Error())
+		return
+	}
+	defer client.StopServing(time.Second)
+
+	if _, err := client.Call("system.exit"); err == nil || err.Error() != "exit status 1" {
+		t.Errorf("Expected `exit status 1`, received %v", err)
+		return
+	}
+
+	if _, err := client.Call("system.info", "cpu"); err == nil {
+		t.Error("Expected an error, received none")
+	}
+}
+
+func TestStartProcess_NotFound(t *testing.T) {
+	if _, err := StartProcess(exec.Command("/nonexistent/jsonrpc")); err == nil {
+		t.Error("Expected an error, received none")
+	}
+}
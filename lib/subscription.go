@@ -0,0 +1,399 @@
+package jsonrpc
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/dekelund/jsonrpc/lib/internal"
+)
+
+// Subscriptions are created by calling a subscribe method, e.g.
+// "prices_subscribe" or "prices", whose handler returns the ID of a
+// Subscription. The server then sends notifications named after the
+// namespace of the method, e.g. "prices_subscription", with the named
+// parameters "subscription", the ID, and "result". The client cancels the
+// subscription by calling e.g. "prices_unsubscribe" with the ID, which is
+// handled by the server.
+
+// maxSubscriptionQueue is how many notifications a client subscription
+// queues for a slow reader before it fails.
+const maxSubscriptionQueue = 1000
+
+var (
+	// ErrSubscriptionsNotSupported is returned by Request.Subscribe if the
+	// server can't send notifications to the caller, e.g. over HTTP.
+	ErrSubscriptionsNotSupported = errors.New("jsonrpc: subscriptions not supported")
+
+	// ErrSubscriptionClosed is returned by Subscription.Notify after the
+	// client unsubscribed or disconnected.
+	ErrSubscriptionClosed = errors.New("jsonrpc: subscription closed")
+
+	// ErrSubscriptionQueueOverflow is sent on ClientSubscription.Err if the
+	// channel isn't read fast enough.
+	ErrSubscriptionQueueOverflow = errors.New("jsonrpc: subscription queue overflow")
+
+	// ErrClientStopped is sent on ClientSubscription.Err if the client is
+	// stopped.
+	ErrClientStopped = errors.New("jsonrpc: client stopped")
+)
+
+// Subscription sends notifications to the connection of the method call
+// which created it, until the client unsubscribes or disconnects.
+type Subscription struct {
+	ID ID
+
+	conn   *conn
+	method string // NOTE: The notification method
+
+	mutex  sync.Mutex
+	active bool              // NOTE: Set once the subscribe call has been responded to
+	queued []json.RawMessage // NOTE: Notifications sent before then
+	closed bool
+	done   chan bool
+}
+
+// Subscribe creates a subscription for the connection of the method call,
+// the handler should return its ID as the result. Notifications sent before
+// the call is responded to are queued until then. If the handler fails the
+// subscription is closed.
+func (req *Request) Subscribe() (*Subscription, error) {
+	if req.conn == nil || req.notification {
+		return nil, ErrSubscriptionsNotSupported
+	}
+
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+
+	sub := &Subscription{
+		ID:     StringID("0x" + hex.EncodeToString(b)),
+		conn:   req.conn,
+		method: namespace(req.Method) + "_subscription",
+		done:   make(chan bool),
+	}
+
+	req.conn.subscribe(sub)
+	req.subscription = sub
+
+	return sub, nil
+}
+
+// Notify sends v as the result of a notification. ErrSubscriptionClosed is
+// returned once the client unsubscribed or disconnected.
+func (sub *Subscription) Notify(v interface{}) error {
+	params, err := internal.NamedParams(struct {
+		Subscription ID          `json:"subscription"`
+		Result       interface{} `json:"result"`
+	}{sub.ID, v})
+	if err != nil {
+		return err
+	}
+
+	sub.mutex.Lock()
+	defer sub.mutex.Unlock()
+
+	if sub.closed {
+		return ErrSubscriptionClosed
+	}
+
+	if !sub.active {
+		sub.queued = append(sub.queued, params)
+		return nil
+	}
+
+	return sub.conn.w.Notify(sub.method, params)
+}
+
+// Done is closed when the client unsubscribes or disconnects.
+func (sub *Subscription) Done() <-chan bool {
+	return sub.done
+}
+
+// activate sends the queued notifications, the subscribe call has been
+// responded to.
+func (sub *Subscription) activate() {
+	sub.mutex.Lock()
+	defer sub.mutex.Unlock()
+
+	sub.active = true
+
+	for _, params := range sub.queued {
+		sub.conn.w.Notify(sub.method, params)
+	}
+
+	sub.queued = nil
+}
+
+func (sub *Subscription) close() {
+	sub.mutex.Lock()
+	defer sub.mutex.Unlock()
+
+	if !sub.closed {
+		sub.closed = true
+		close(sub.done)
+	}
+}
+
+func (c *conn) subscribe(sub *Subscription) {
+	c.subMutex.Lock()
+	defer c.subMutex.Unlock()
+
+	c.subscriptions[sub.ID] = sub
+}
+
+// unsubscribe closes the subscription, if it was created by a method of
+// the namespace.
+func (c *conn) unsubscribe(id ID, ns string) bool {
+	c.subMutex.Lock()
+	defer c.subMutex.Unlock()
+
+	sub, ok := c.subscriptions[id]
+	if !ok || !strings.HasPrefix(sub.method, ns+"_") {
+		return false
+	}
+
+	delete(c.subscriptions, id)
+	sub.close()
+
+	return true
+}
+
+func (c *conn) closeSubscriptions() {
+	c.subMutex.Lock()
+	defer c.subMutex.Unlock()
+
+	for id, sub := range c.subscriptions {
+		delete(c.subscriptions, id)
+		sub.close()
+	}
+}
+
+// serveUnsubscribe handles the unsubscribe method of a namespace, the
+// result is false if there was no such subscription.
+func (c *conn) serveUnsubscribe(req *Request) (interface{}, error) {
+	var id ID
+	if len(req.Params) != 1 {
+		return nil, Error{errInvalidParams}
+	} else if s, ok := req.Params[0].(string); !ok {
+		return nil, Error{errInvalidParams}
+	} else {
+		id = StringID(s)
+	}
+
+	return c.unsubscribe(id, strings.TrimSuffix(req.Method, "_unsubscribe")), nil
+}
+
+// namespace returns the namespace of a subscribe method, the method name
+// without any "_subscribe" suffix.
+func namespace(method string) string {
+	return strings.TrimSuffix(method, "_subscribe")
+}
+
+// ClientSubscription receives the notifications of a subscription, see
+// Client.Subscribe.
+type ClientSubscription struct {
+	ID ID
+
+	client  *Client
+	method  string // NOTE: The unsubscribe method
+	channel reflect.Value
+
+	queue chan json.RawMessage
+	quit  chan bool
+	once  sync.Once
+	err   chan error
+}
+
+// Subscribe calls the subscribe method and sends the results of the
+// notifications of the subscription on channel, a channel of the type the
+// results are decoded into, until Unsubscribe is called or the subscription
+// fails, see Err. The channel isn't closed.
+func (client *Client) Subscribe(ctx context.Context, channel interface{}, method string, params ...interface{}) (*ClientSubscription, error) {
+	ch := reflect.ValueOf(channel)
+	if ch.Kind() != reflect.Chan || ch.Type().ChanDir()&reflect.SendDir == 0 {
+		panic("jsonrpc: subscription channel must be a writable channel")
+	}
+
+	client.subMutex.Lock()
+	client.subscribing++
+	client.subMutex.Unlock()
+
+	var id ID
+	err := client.CallInto(ctx, &id, method, params...)
+
+	client.subMutex.Lock()
+	defer client.subMutex.Unlock()
+
+	if client.subscribing--; client.subscribing == 0 {
+		defer func() { client.early = nil }()
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	sub := &ClientSubscription{
+		ID:      id,
+		client:  client,
+		method:  namespace(method) + "_unsubscribe",
+		channel: ch,
+
+		queue: make(chan json.RawMessage, maxSubscriptionQueue),
+		quit:  make(chan bool),
+		err:   make(chan error, 1),
+	}
+
+	client.subscriptions[id] = sub
+	go sub.forward()
+
+	// NOTE: Notifications may overtake the response
+	early := client.early[:0]
+	for _, m := range client.early {
+		if subscription, result, ok := notificationParams(m); ok && subscription == id {
+			sub.deliver(result)
+		} else {
+			early = append(early, m)
+		}
+	}
+	client.early = early
+
+	return sub, nil
+}
+
+// notification passes a notification to its subscription, it's queued if
+// the subscription may not be known yet. It returns false if m isn't a
+// subscription notification.
+func (client *Client) notification(m internal.Method) bool {
+	id, result, ok := notificationParams(m)
+	if !ok {
+		return false
+	}
+
+	client.subMutex.Lock()
+	defer client.subMutex.Unlock()
+
+	if sub, ok := client.subscriptions[id]; ok {
+		sub.deliver(result)
+	} else if client.subscribing > 0 {
+		client.early = append(client.early, m)
+	}
+
+	return true
+}
+
+func (client *Client) closeSubscriptions(err error) {
+	client.subMutex.Lock()
+	defer client.subMutex.Unlock()
+
+	for id, sub := range client.subscriptions {
+		delete(client.subscriptions, id)
+		sub.close(err)
+	}
+}
+
+func notificationParams(m internal.Method) (ID, json.RawMessage, bool) {
+	var params struct {
+		Subscription ID              `json:"subscription"`
+		Result       json.RawMessage `json:"result"`
+	}
+
+	if m.ID != nil || !strings.HasSuffix(m.Method, "_subscription") || m.NamedParams == nil {
+		return ID{}, nil, false
+	}
+
+	if err := json.Unmarshal(m.NamedParams, &params); err != nil || params.Subscription.IsNull() {
+		return ID{}, nil, false
+	}
+
+	return params.Subscription, params.Result, true
+}
+
+// Unsubscribe stops sending notifications on the channel and cancels the
+// subscription on the server. Err is closed without an error.
+func (sub *ClientSubscription) Unsubscribe() {
+	sub.client.subMutex.Lock()
+	delete(sub.client.subscriptions, sub.ID)
+	sub.client.subMutex.Unlock()
+
+	if sub.close(nil) {
+		sub.client.Call(sub.method, sub.ID)
+	}
+}
+
+// Err receives the error failing the subscription, e.g. io.EOF if the
+// connection is closed or ErrSubscriptionQueueOverflow, and is then closed.
+func (sub *ClientSubscription) Err() <-chan error {
+	return sub.err
+}
+
+// deliver queues the result of a notification, the client's subMutex is
+// held.
+func (sub *ClientSubscription) deliver(result json.RawMessage) {
+	select {
+	case sub.queue <- result:
+	default:
+		delete(sub.client.subscriptions, sub.ID)
+
+		if sub.close(ErrSubscriptionQueueOverflow) {
+			go sub.client.Call(sub.method, sub.ID)
+		}
+	}
+}
+
+// forward decodes the queued results and sends them on the channel.
+func (sub *ClientSubscription) forward() {
+	cases := []reflect.SelectCase{
+		{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(sub.quit)},
+		{Dir: reflect.SelectSend, Chan: sub.channel},
+	}
+
+	for {
+		select {
+		case result := <-sub.queue:
+			v := reflect.New(sub.channel.Type().Elem())
+			if err := json.Unmarshal(result, v.Interface()); err != nil {
+				sub.client.subMutex.Lock()
+				delete(sub.client.subscriptions, sub.ID)
+				sub.client.subMutex.Unlock()
+
+				if sub.close(err) {
+					sub.client.Call(sub.method, sub.ID)
+				}
+
+				return
+			}
+
+			cases[1].Send = v.Elem()
+			if chosen, _, _ := reflect.Select(cases); chosen == 0 {
+				return
+			}
+		case <-sub.quit:
+			return
+		}
+	}
+}
+
+// close stops the subscription, it returns false if it was already
+// stopped.
+func (sub *ClientSubscription) close(err error) bool {
+	closed := false
+
+	sub.once.Do(func() {
+		closed = true
+		close(sub.quit)
+
+		if err != nil {
+			sub.err <- err
+		}
+		close(sub.err)
+	})
+
+	return closed
+}
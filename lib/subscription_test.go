@@ -0,0 +1,190 @@
+package jsonrpc
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// newTestSubscriptionServer returns a server whose "prices_subscribe"
+// method notifies the prices given as parameters, a client calling it and
+// a function disconnecting them.
+func newTestSubscriptionServer(opts ...Option) (*Server, *Client, chan *Subscription, func()) {
+	sr, cw := io.Pipe()
+	cr, sw := io.Pipe()
+
+	server := NewServer(sr, sw, opts...)
+	subs := make(chan *Subscription, 1)
+
+	server.HandleFunc("prices_subscribe", func(req *Request) (interface{}, error) {
+		sub, err := req.Subscribe()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, price := range req.Params {
+			sub.Notify(price) // NOTE: Queued until the response is sent
+		}
+
+		subs <- sub
+		return sub.ID, nil
+	})
+
+	disconnect := func() {
+		cw.Close()
+		sw.Close()
+	}
+
+	return server, NewClient(cr, cw, opts...), subs, disconnect
+}
+
+func TestClient_Subscribe(t *testing.T) {
+	server, client, subs, _ := newTestSubscriptionServer()
+	defer server.StopServing(time.Second)
+	defer client.StopServing(time.Second)
+
+	prices := make(chan int)
+
+	sub, err := client.Subscribe(context.Background(), prices, "prices_subscribe", 1, 2)
+	if err != nil {
+		t.Errorf("Expected no error, received %s", err.Error())
+		return
+	}
+
+	serverSub := <-subs
+	serverSub.Notify(3)
+
+	for _, expected := range []int{1, 2, 3} {
+		if price := <-prices; price != expected {
+			t.Errorf("Expected %d, received %d", expected, price)
+			return
+		}
+	}
+
+	sub.Unsubscribe()
+
+	select {
+	case <-serverSub.Done():
+	case <-time.After(time.Second):
+		t.Error("Expected the server subscription to be closed")
+		return
+	}
+
+	if err := serverSub.Notify(4); err != ErrSubscriptionClosed {
+		t.Errorf("Expected ErrSubscriptionClosed, received %v", err)
+		return
+	}
+
+	if err, more := <-sub.Err(); err != nil || more {
+		t.Errorf("Expected Err to be closed, received %v", err)
+	}
+}
+
+func TestClient_SubscribeDisconnect(t *testing.T) {
+	server, client, subs, disconnect := newTestSubscriptionServer()
+	defer server.StopServing(time.Second)
+	defer client.StopServing(time.Second)
+
+	sub, err := client.Subscribe(context.Background(), make(chan int), "prices_subscribe")
+	if err != nil {
+		t.Errorf("Expected no error, received %s", err.Error())
+		return
+	}
+
+	serverSub := <-subs
+	disconnect()
+
+	select {
+	case <-serverSub.Done():
+	case <-time.After(time.Second):
+		t.Error("Expected the server subscription to be closed")
+		return
+	}
+
+	if err := <-sub.Err(); err != io.EOF {
+		t.Errorf("Expected io.EOF, received %v", err)
+	}
+}
+
+func TestClient_SubscribeStopServing(t *testing.T) {
+	server, client, _, _ := newTestSubscriptionServer()
+	defer server.StopServing(time.Second)
+
+	sub, err := client.Subscribe(context.Background(), make(chan int), "prices_subscribe")
+	if err != nil {
+		t.Errorf("Expected no error, received %s", err.Error())
+		return
+	}
+
+	client.StopServing(time.Second)
+
+	if err := <-sub.Err(); err != ErrClientStopped && err != io.EOF {
+		t.Errorf("Expected ErrClientStopped, received %v", err)
+	}
+}
+
+func TestClient_SubscribeError(t *testing.T) {
+	server, client, _, _ := newTestSubscriptionServer()
+	defer server.StopServing(time.Second)
+	defer client.StopServing(time.Second)
+
+	if _, err := client.Subscribe(context.Background(), make(chan int), "volumes_subscribe"); err == nil {
+		t.Error("Expected an error, received none")
+	}
+}
+
+func TestClient_SubscribeHTTP(t *testing.T) {
+	server, _, _, _ := newTestSubscriptionServer()
+	defer server.StopServing(time.Second)
+
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	client := NewHTTPClient(ts.URL)
+	defer client.StopServing(time.Second)
+
+	if _, err := client.Subscribe(context.Background(), make(chan int), "prices_subscribe"); err == nil || err.Error() != ErrSubscriptionsNotSupported.Error() {
+		t.Errorf("Expected ErrSubscriptionsNotSupported, received %v", err)
+	}
+}
+
+func TestPeer_Subscribe(t *testing.T) {
+	a, b := newTestPeers()
+	defer a.StopServing(time.Second)
+	defer b.StopServing(time.Second)
+
+	b.HandleFunc("ticks_subscribe", func(req *Request) (interface{}, error) {
+		sub, err := req.Subscribe()
+		if err != nil {
+			return nil, err
+		}
+
+		go func() {
+			for i := 0; ; i++ {
+				if sub.Notify(i) != nil {
+					return
+				}
+			}
+		}()
+
+		return sub.ID, nil
+	})
+
+	ticks := make(chan int)
+
+	sub, err := a.Subscribe(context.Background(), ticks, "ticks_subscribe")
+	if err != nil {
+		t.Errorf("Expected no error, received %s", err.Error())
+		return
+	}
+	defer sub.Unsubscribe()
+
+	for expected := 0; expected < 3; expected++ {
+		if tick := <-ticks; tick != expected {
+			t.Errorf("Expected %d, received %d", expected, tick)
+			return
+		}
+	}
+}
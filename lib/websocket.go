@@ -0,0 +1,109 @@
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// errBinaryFrame fails a WebSocket connection on which a binary frame is
+// received, messages are sent as text frames.
+var errBinaryFrame = errors.New("jsonrpc: binary WebSocket frames not supported")
+
+// ServeWebSocket upgrades the request to a WebSocket connection and serves
+// the method calls read from it, one message per text frame, until the
+// peer closes it. Use it as a handler with http.HandlerFunc, see
+// WithCheckOrigin to accept cross-origin requests.
+func (server *Server) ServeWebSocket(w http.ResponseWriter, req *http.Request) {
+	upgrader := websocket.Upgrader{CheckOrigin: server.opts.checkOrigin}
+
+	conn, err := upgrader.Upgrade(w, req, nil)
+	if err != nil {
+		return // NOTE: Upgrade has responded with an HTTP error
+	}
+
+	ws := &wsConn{conn: conn}
+	server.serveConn(ws, ws) // NOTE: Frames delimit the messages
+}
+
+// DialWebSocket connects to the WebSocket server at url, e.g.
+// "ws://localhost:8080/rpc", and returns a client calling methods over the
+// connection. The connection is closed by StopServing.
+func DialWebSocket(ctx context.Context, url string, opts ...Option) (*Client, error) {
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	ws := &wsConn{conn: conn}
+
+	client := NewClient(ws, ws, append(opts[:len(opts):len(opts)], WithFramer(ws))...) // NOTE: Frames delimit the messages
+	client.closer = ws
+
+	return client, nil
+}
+
+// wsConn is its own Framer, it reads and writes each message as a text
+// frame of its own.
+type wsConn struct {
+	conn *websocket.Conn
+}
+
+func (c *wsConn) NewFrameReader(_ io.Reader, maxSize int) FrameReader {
+	c.conn.SetReadLimit(int64(maxSize))
+	return c
+}
+
+func (c *wsConn) NewFrameWriter(_ io.Writer) FrameWriter {
+	return c
+}
+
+// ReadFrame reads the next message, binary frames fail the connection.
+func (c *wsConn) ReadFrame() ([]byte, error) {
+	typ, r, err := c.conn.NextReader()
+	if _, ok := err.(*websocket.CloseError); ok {
+		return nil, io.EOF
+	} else if err != nil {
+		return nil, err
+	}
+
+	if typ != websocket.TextMessage {
+		msg := websocket.FormatCloseMessage(websocket.CloseUnsupportedData, errBinaryFrame.Error())
+		c.conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(time.Second))
+
+		return nil, errBinaryFrame
+	}
+
+	b, err := ioutil.ReadAll(r)
+	if err == websocket.ErrReadLimit {
+		return nil, ErrFrameTooLarge
+	}
+
+	return b, err
+}
+
+func (c *wsConn) WriteFrame(msg []byte) error {
+	return c.conn.WriteMessage(websocket.TextMessage, msg)
+}
+
+// Read isn't used, the messages are read with ReadFrame.
+func (c *wsConn) Read(p []byte) (int, error) {
+	return 0, errors.New("jsonrpc: WebSocket messages are read as frames")
+}
+
+// Write isn't used, the messages are written with WriteFrame.
+func (c *wsConn) Write(p []byte) (int, error) {
+	return 0, errors.New("jsonrpc: WebSocket messages are written as frames")
+}
+
+func (c *wsConn) Close() error {
+	msg := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+	c.conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(time.Second))
+
+	return c.conn.Close()
+}
@@ -0,0 +1,134 @@
+package jsonrpc
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestWebSocket(t *testing.T) {
+	server := NewServer(nil, nil)
+	defer server.StopServing(time.Second)
+
+	server.HandleFunc("system.info", func(req *Request) (interface{}, error) {
+		return req.Params[0], nil
+	})
+
+	ts := httptest.NewServer(http.HandlerFunc(server.ServeWebSocket))
+	defer ts.Close()
+
+	client, err := DialWebSocket(context.Background(), "ws"+strings.TrimPrefix(ts.URL, "http"))
+	if err != nil {
+		t.Errorf("Expected no error, received %s", err.Error())
+		return
+	}
+	defer client.StopServing(time.Second)
+
+	for _, param := range []string{"cpu", "mem"} {
+		if result, err := client.Call("system.info", param); err != nil || result != param {
+			t.Errorf("Expected `%s`, received %v, %v", param, result, err)
+			return
+		}
+	}
+}
+
+func TestWebSocket_TextFrames(t *testing.T) {
+	server := NewServer(nil, nil)
+	defer server.StopServing(time.Second)
+
+	server.HandleFunc("system.info", func(req *Request) (interface{}, error) {
+		return "ok", nil
+	})
+
+	ts := httptest.NewServer(http.HandlerFunc(server.ServeWebSocket))
+	defer ts.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http"), nil)
+	if err != nil {
+		t.Errorf("Expected no error, received %s", err.Error())
+		return
+	}
+	defer conn.Close()
+
+	conn.WriteMessage(websocket.TextMessage, []byte(`{"id":1,"method":"system.info","params":[]}`))
+	conn.WriteMessage(websocket.TextMessage, []byte(`{"id":2,"method":"system.info","params":[]}`))
+
+	for _, expectedMSG := range []string{`{"id":1,"result":"ok"}`, `{"id":2,"result":"ok"}`} {
+		typ, msg, err := conn.ReadMessage()
+		if err != nil {
+			t.Errorf("Expected no error, received %s", err.Error())
+			return
+		}
+
+		if typ != websocket.TextMessage || string(msg) != expectedMSG {
+			t.Errorf("Expected text frame `%s`, received `%s`", expectedMSG, msg)
+			return
+		}
+	}
+}
+
+func TestWebSocket_OneMessagePerFrame(t *testing.T) {
+	server := NewServer(nil, nil)
+	defer server.StopServing(time.Second)
+
+	server.HandleFunc("system.info", func(req *Request) (interface{}, error) {
+		return "ok", nil
+	})
+
+	ts := httptest.NewServer(http.HandlerFunc(server.ServeWebSocket))
+	defer ts.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http"), nil)
+	if err != nil {
+		t.Errorf("Expected no error, received %s", err.Error())
+		return
+	}
+	defer conn.Close()
+
+	conn.WriteMessage(websocket.TextMessage, []byte(`{"id":1,"method":`)) // NOTE: Not continued by the next frame
+	conn.WriteMessage(websocket.TextMessage, []byte(`{"id":2,"method":"system.info","params":[]}`))
+
+	for _, expectedMSG := range []string{`{"id":null,"error":{"code":-32700,"message":"Parse error"}}`, `{"id":2,"result":"ok"}`} {
+		if _, msg, err := conn.ReadMessage(); err != nil || string(msg) != expectedMSG {
+			t.Errorf("Expected `%s`, received `%s`, %v", expectedMSG, msg, err)
+			return
+		}
+	}
+
+	conn.WriteMessage(websocket.BinaryMessage, []byte(`{"id":3,"method":"system.info","params":[]}`))
+
+	if _, _, err := conn.ReadMessage(); !websocket.IsCloseError(err, websocket.CloseUnsupportedData) {
+		t.Errorf("Expected close code %d, received %v", websocket.CloseUnsupportedData, err)
+	}
+}
+
+func TestWebSocket_CheckOrigin(t *testing.T) {
+	for _, test := range []struct {
+		opts   []Option
+		status int
+	}{
+		{nil, http.StatusForbidden},
+		{[]Option{WithCheckOrigin(func(req *http.Request) bool { return true })}, http.StatusSwitchingProtocols},
+	} {
+		server := NewServer(nil, nil, test.opts...)
+		ts := httptest.NewServer(http.HandlerFunc(server.ServeWebSocket))
+
+		header := http.Header{"Origin": {"http://dashboard.example.com"}}
+		conn, resp, _ := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http"), header)
+		if conn != nil {
+			conn.Close()
+		}
+
+		if resp == nil || resp.StatusCode != test.status {
+			t.Errorf("Expected %d, received %v", test.status, resp)
+		}
+
+		ts.Close()
+		server.StopServing(time.Second)
+	}
+}